@@ -0,0 +1,367 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WorkerPool replaces the old `guard := make(chan struct{}, threads)`
+// semaphore with one that --dashboard can resize and pause/resume at
+// runtime; a plain channel can't grow or shrink once created.
+type WorkerPool struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	max    int
+	active int
+	paused bool
+}
+
+func NewWorkerPool(threads int) *WorkerPool {
+	p := &WorkerPool{max: threads}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// Acquire blocks until a slot is free and the pool isn't paused.
+func (p *WorkerPool) Acquire() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.paused || p.active >= p.max {
+		p.cond.Wait()
+	}
+	p.active++
+}
+
+func (p *WorkerPool) Release() {
+	p.mu.Lock()
+	p.active--
+	p.cond.Broadcast()
+	p.mu.Unlock()
+}
+
+// Resize changes how many workers may run concurrently; waiters are woken
+// so a larger pool can pick up the new slots immediately.
+func (p *WorkerPool) Resize(n int) {
+	if n < 1 {
+		n = 1
+	}
+	p.mu.Lock()
+	p.max = n
+	p.cond.Broadcast()
+	p.mu.Unlock()
+}
+
+func (p *WorkerPool) Pause() {
+	p.mu.Lock()
+	p.paused = true
+	p.mu.Unlock()
+}
+
+func (p *WorkerPool) Resume() {
+	p.mu.Lock()
+	p.paused = false
+	p.cond.Broadcast()
+	p.mu.Unlock()
+}
+
+func (p *WorkerPool) Snapshot() (max, active int, paused bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.max, p.active, p.paused
+}
+
+// RunStats is the atomic-counter state behind the dashboard's stats panel;
+// kept separate from the package-level `stats`/`metrics` used by ccwarc
+// since cclinks runs are scoped per (archive, segments) call rather than
+// per process.
+type RunStats struct {
+	FilesTotal   int64
+	FilesDone    int64
+	Results      int64
+	Errors       int64
+	BytesFetched int64
+	startedAt    time.Time
+}
+
+func newRunStats(filesTotal int) *RunStats {
+	return &RunStats{FilesTotal: int64(filesTotal), startedAt: time.Now()}
+}
+
+func (s *RunStats) AddFilesTotal(n int64) { atomic.AddInt64(&s.FilesTotal, n) }
+func (s *RunStats) IncFiles()             { atomic.AddInt64(&s.FilesDone, 1) }
+func (s *RunStats) IncResults(n int64)    { atomic.AddInt64(&s.Results, n) }
+func (s *RunStats) IncErrors()            { atomic.AddInt64(&s.Errors, 1) }
+func (s *RunStats) AddBytes(n int64)      { atomic.AddInt64(&s.BytesFetched, n) }
+
+func (s *RunStats) snapshot() map[string]interface{} {
+	elapsed := time.Since(s.startedAt).Seconds()
+	bytes := atomic.LoadInt64(&s.BytesFetched)
+	bytesPerSec := 0.0
+	if elapsed > 0 {
+		bytesPerSec = float64(bytes) / elapsed
+	}
+	return map[string]interface{}{
+		"files_total":     atomic.LoadInt64(&s.FilesTotal),
+		"files_done":      atomic.LoadInt64(&s.FilesDone),
+		"results_found":   atomic.LoadInt64(&s.Results),
+		"errors":          atomic.LoadInt64(&s.Errors),
+		"bytes_per_sec":   bytesPerSec,
+		"elapsed_seconds": elapsed,
+	}
+}
+
+// liveFilter wraps the OutlinkFilter a run started with so --dashboard can
+// mutate it (e.g. add an exclude keyword) while workers are mid-flight. It's
+// backed by an atomic.Pointer rather than a mutex so a worker's lf.Get() in
+// the hot path never blocks on a dashboard request swapping it out.
+type liveFilter struct {
+	p atomic.Pointer[OutlinkFilter]
+}
+
+func newLiveFilter(f OutlinkFilter) *liveFilter {
+	lf := &liveFilter{}
+	lf.p.Store(&f)
+	return lf
+}
+
+func (lf *liveFilter) Get() OutlinkFilter {
+	return *lf.p.Load()
+}
+
+// update swaps in a copy of the current filter after mutate has modified it,
+// giving every mutator here copy-on-write semantics without a shared lock.
+func (lf *liveFilter) update(mutate func(*OutlinkFilter)) {
+	f := lf.Get()
+	mutate(&f)
+	lf.p.Store(&f)
+}
+
+func (lf *liveFilter) AddExcludeKeyword(kw string) {
+	lf.update(func(f *OutlinkFilter) { f.ExcludeKeywords = append(f.ExcludeKeywords, kw) })
+}
+
+func (lf *liveFilter) AddURLKeyword(kw string) {
+	lf.update(func(f *OutlinkFilter) { f.URLKeywords = append(f.URLKeywords, kw) })
+}
+
+func (lf *liveFilter) AddCountryTLD(tld string) {
+	lf.update(func(f *OutlinkFilter) { f.CountryTLDs = append(f.CountryTLDs, tld) })
+}
+
+func (lf *liveFilter) SetMinAnchorLength(n int) {
+	lf.update(func(f *OutlinkFilter) { f.MinAnchorLength = n })
+}
+
+// resultTail keeps the last N NDJSON result lines in a ring buffer and fans
+// out every new line to whatever dashboards are currently watching
+// /api/tail, so --dashboard can show a live feed without re-reading the
+// output file.
+type resultTail struct {
+	mu   sync.Mutex
+	buf  []string
+	max  int
+	next int
+	subs map[chan string]struct{}
+}
+
+func newResultTail(max int) *resultTail {
+	return &resultTail{max: max, subs: make(map[chan string]struct{})}
+}
+
+// Add records a newly-written NDJSON line and pushes it to live subscribers.
+func (t *resultTail) Add(line string) {
+	t.mu.Lock()
+	if len(t.buf) < t.max {
+		t.buf = append(t.buf, line)
+	} else {
+		t.buf[t.next%t.max] = line
+		t.next++
+	}
+	for ch := range t.subs {
+		select {
+		case ch <- line:
+		default: // slow subscriber; drop rather than block the run
+		}
+	}
+	t.mu.Unlock()
+}
+
+// recent returns the buffered lines in the order they were added.
+func (t *resultTail) recent() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.buf) < t.max {
+		out := make([]string, len(t.buf))
+		copy(out, t.buf)
+		return out
+	}
+	out := make([]string, 0, t.max)
+	for i := 0; i < t.max; i++ {
+		out = append(out, t.buf[(t.next+i)%t.max])
+	}
+	return out
+}
+
+func (t *resultTail) subscribe() chan string {
+	ch := make(chan string, 16)
+	t.mu.Lock()
+	t.subs[ch] = struct{}{}
+	t.mu.Unlock()
+	return ch
+}
+
+func (t *resultTail) unsubscribe(ch chan string) {
+	t.mu.Lock()
+	delete(t.subs, ch)
+	t.mu.Unlock()
+}
+
+const dashboardHTML = `<!DOCTYPE html>
+<html><head><title>cclinks dashboard</title>
+<meta http-equiv="refresh" content="2">
+<style>body{font-family:monospace;margin:2em}table{border-collapse:collapse}td,th{padding:4px 12px;text-align:left}</style>
+</head><body>
+<h2>cclinks extraction run</h2>
+<table id="stats"></table>
+<p><a href="/api/stats">/api/stats</a> &middot; POST /api/pause &middot; POST /api/resume &middot; POST /api/threads?n=N &middot; POST /api/filter?exclude=|url-keyword=|tld=|min-anchor= &middot; <a href="/api/tail">/api/tail</a> (SSE)</p>
+<script>
+fetch('/api/stats').then(r => r.json()).then(d => {
+  const t = document.getElementById('stats')
+  for (const k in d) {
+    const row = t.insertRow()
+    row.insertCell().textContent = k
+    row.insertCell().textContent = d[k]
+  }
+})
+</script>
+</body></html>`
+
+// startDashboard serves the --dashboard=HOST:PORT control/monitoring
+// endpoints for an in-flight extract/backlinks/sniper run. It runs in the
+// background for the life of the process; there is no graceful shutdown
+// since the run itself ends the process when it's done. lf and tail may be
+// nil (sniper has no OutlinkFilter to hot-swap and callers that don't pass a
+// result tail just don't get one); both are guarded accordingly below.
+func startDashboard(addr string, pool *WorkerPool, stats *RunStats, lf *liveFilter, tail *resultTail) {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(dashboardHTML))
+	})
+
+	mux.HandleFunc("/api/stats", func(w http.ResponseWriter, r *http.Request) {
+		snap := stats.snapshot()
+		max, active, paused := pool.Snapshot()
+		snap["threads_max"] = max
+		snap["threads_active"] = active
+		snap["paused"] = paused
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(snap)
+	})
+
+	mux.HandleFunc("/api/pause", func(w http.ResponseWriter, r *http.Request) {
+		pool.Pause()
+		fmt.Fprintln(w, "paused")
+	})
+
+	mux.HandleFunc("/api/resume", func(w http.ResponseWriter, r *http.Request) {
+		pool.Resume()
+		fmt.Fprintln(w, "resumed")
+	})
+
+	mux.HandleFunc("/api/threads", func(w http.ResponseWriter, r *http.Request) {
+		n, err := strconv.Atoi(r.URL.Query().Get("n"))
+		if err != nil || n < 1 {
+			http.Error(w, "n must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		pool.Resize(n)
+		fmt.Fprintf(w, "threads set to %d\n", n)
+	})
+
+	mux.HandleFunc("/api/filter", func(w http.ResponseWriter, r *http.Request) {
+		if lf == nil {
+			http.Error(w, "this run has no hot-swappable filter", http.StatusNotFound)
+			return
+		}
+
+		applied := false
+		if exclude := r.URL.Query().Get("exclude"); exclude != "" {
+			lf.AddExcludeKeyword(exclude)
+			fmt.Fprintf(w, "added exclude keyword: %s\n", exclude)
+			applied = true
+		}
+		if keyword := r.URL.Query().Get("url-keyword"); keyword != "" {
+			lf.AddURLKeyword(keyword)
+			fmt.Fprintf(w, "added url keyword: %s\n", keyword)
+			applied = true
+		}
+		if tld := r.URL.Query().Get("tld"); tld != "" {
+			lf.AddCountryTLD(tld)
+			fmt.Fprintf(w, "added country TLD: %s\n", tld)
+			applied = true
+		}
+		if minAnchor := r.URL.Query().Get("min-anchor"); minAnchor != "" {
+			n, err := strconv.Atoi(minAnchor)
+			if err != nil || n < 0 {
+				http.Error(w, "min-anchor must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			lf.SetMinAnchorLength(n)
+			fmt.Fprintf(w, "min anchor length set to %d\n", n)
+			applied = true
+		}
+
+		if !applied {
+			http.Error(w, "one of exclude, url-keyword, tld, min-anchor is required", http.StatusBadRequest)
+		}
+	})
+
+	mux.HandleFunc("/api/tail", func(w http.ResponseWriter, r *http.Request) {
+		if tail == nil {
+			http.Error(w, "this run has no result tail", http.StatusNotFound)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+
+		for _, line := range tail.recent() {
+			fmt.Fprintf(w, "data: %s\n\n", line)
+		}
+		flusher.Flush()
+
+		ch := tail.subscribe()
+		defer tail.unsubscribe(ch)
+
+		for {
+			select {
+			case line := <-ch:
+				fmt.Fprintf(w, "data: %s\n\n", line)
+				flusher.Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("⚠️  dashboard server stopped: %v\n", err)
+		}
+	}()
+	log.Printf("🖥️  Dashboard listening on http://%s\n", addr)
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kris-dev-hub/globallinks/pkg/commoncrawl"
+	"github.com/kris-dev-hub/globallinks/pkg/fileutils"
+
+	"jester/scraping/go/pkg/harvest"
+)
+
+// downloadConcurrency caps how many --download-docs fetches run at once,
+// independent of --threads (which governs WAT processing).
+const downloadConcurrency = 4
+
+func handleHarvestCommand() {
+	archive := getArgValue("--archive")
+	if archive == "" {
+		log.Println("Error: --archive parameter is required")
+		os.Exit(1)
+	}
+	if !commoncrawl.IsCorrectArchiveFormat(archive) {
+		log.Println("Error: Invalid archive format")
+		os.Exit(1)
+	}
+
+	var targetDomains []string
+	if domains := getArgValue("--domains"); domains != "" {
+		targetDomains = strings.Split(domains, ",")
+	}
+
+	segments := getArgValue("--segments")
+	if segments == "" {
+		segments = "0"
+	}
+
+	kinds := []string{harvest.KindEmails, harvest.KindDocs}
+	if k := getArgValue("--kinds"); k != "" {
+		kinds = strings.Split(k, ",")
+	}
+	wantEmails := containsKind(kinds, harvest.KindEmails)
+	wantDocs := containsKind(kinds, harvest.KindDocs)
+
+	var docExts []string
+	if exts := getArgValue("--doc-exts"); exts != "" {
+		docExts = strings.Split(exts, ",")
+	}
+	docFinder := harvest.NewDocFinder(docExts)
+
+	downloadDocs := hasArg("--download-docs")
+	docsDir := getArgValue("--docs-dir")
+	if downloadDocs {
+		if docsDir == "" {
+			docsDir = "harvested_docs"
+		}
+		if err := fileutils.CreateDataDirectory(docsDir); err != nil {
+			log.Fatalf("could not create --docs-dir %s: %v", docsDir, err)
+		}
+	}
+
+	threads := getIntArg("--threads", 2)
+	outputDir := getArgValue("--output")
+	if outputDir == "" {
+		outputDir = fmt.Sprintf("harvest_%s_%d", archive, time.Now().Unix())
+	}
+	if err := fileutils.CreateDataDirectory(outputDir); err != nil {
+		log.Fatalf("could not create output directory %s: %v", outputDir, err)
+	}
+
+	log.Printf("📨 Starting document/email harvest...\n")
+	log.Printf("📁 Archive: %s\n", archive)
+	log.Printf("🎯 Kinds: %v\n", kinds)
+	log.Printf("💾 Output: %s\n", outputDir)
+
+	err := harvestRun(archive, segments, targetDomains, wantEmails, wantDocs, docFinder, downloadDocs, docsDir, threads, outputDir)
+	if err != nil {
+		log.Fatalf("Harvest failed: %v", err)
+	}
+
+	log.Printf("✅ Harvest completed! Results saved to: %s\n", outputDir)
+}
+
+// containsKind reports whether kind appears (case-insensitively) in kinds.
+func containsKind(kinds []string, kind string) bool {
+	for _, k := range kinds {
+		if strings.EqualFold(strings.TrimSpace(k), kind) {
+			return true
+		}
+	}
+	return false
+}
+
+func harvestRun(archive, segments string, targetDomains []string, wantEmails, wantDocs bool, docFinder harvest.DocFinder, downloadDocs bool, docsDir string, threads int, outputDir string) error {
+	segmentList, err := commoncrawl.InitImport(archive)
+	if err != nil {
+		return fmt.Errorf("could not load segment list: %v", err)
+	}
+
+	segmentsToProcess, err := parseSegmentInput(segments)
+	if err != nil {
+		return fmt.Errorf("invalid segment input: %v", err)
+	}
+
+	var emailsFile, docsFile *os.File
+	if wantEmails {
+		emailsFile, err = os.Create(filepath.Join(outputDir, "emails.txt"))
+		if err != nil {
+			return fmt.Errorf("could not create emails.txt: %v", err)
+		}
+		defer emailsFile.Close()
+	}
+	if wantDocs {
+		docsFile, err = os.Create(filepath.Join(outputDir, "documents.ndjson"))
+		if err != nil {
+			return fmt.Errorf("could not create documents.ndjson: %v", err)
+		}
+		defer docsFile.Close()
+	}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	pool := NewWorkerPool(threads)
+
+	downloadGuard := make(chan struct{}, downloadConcurrency)
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+
+	emailsFound := 0
+	docsFound := 0
+
+	for _, segmentID := range segmentsToProcess {
+		segment, err := commoncrawl.SelectSegmentByID(segmentList, segmentID)
+		if err != nil {
+			log.Printf("⚠️ Warning: Segment %d not found\n", segmentID)
+			continue
+		}
+
+		log.Printf("🔄 Processing segment %d...\n", segmentID)
+
+		for _, watFile := range segment.WatFiles {
+			wg.Add(1)
+			pool.Acquire()
+
+			go func(watPath string) {
+				defer wg.Done()
+				defer pool.Release()
+
+				emails, docs, err := processWATFileForHarvest(watPath, targetDomains, wantEmails, wantDocs, docFinder)
+				if err != nil {
+					log.Printf("❌ Error processing WAT file %s: %v", watPath, err)
+					return
+				}
+
+				mutex.Lock()
+				for _, e := range emails {
+					emailsFile.WriteString(fmt.Sprintf("%s\t%s\n", e.Email, e.SourceURL))
+					emailsFound++
+				}
+				for _, d := range docs {
+					body, _ := json.Marshal(d)
+					docsFile.Write(body)
+					docsFile.WriteString("\n")
+					docsFound++
+				}
+				mutex.Unlock()
+
+				if downloadDocs {
+					for _, d := range docs {
+						downloadDocument(httpClient, d, docsDir, downloadGuard)
+					}
+				}
+			}(watFile.Path)
+		}
+	}
+
+	wg.Wait()
+
+	log.Printf("📊 Harvest complete: %d emails, %d documents\n", emailsFound, docsFound)
+	return nil
+}
+
+// processWATFileForHarvest scans one WAT file's pages (optionally restricted
+// to targetDomains) for emails and document links.
+func processWATFileForHarvest(watPath string, targetDomains []string, wantEmails, wantDocs bool, docFinder harvest.DocFinder) ([]harvest.EmailMatch, []harvest.DocLink, error) {
+	tempDir, err := os.MkdirTemp("", "wat_harvest_*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	localWatFile := filepath.Join(tempDir, filepath.Base(watPath))
+	if err := fileutils.DownloadFile("https://data.commoncrawl.org/"+watPath, localWatFile, 2); err != nil {
+		return nil, nil, fmt.Errorf("could not download WAT file: %v", err)
+	}
+	defer os.Remove(localWatFile)
+
+	file, err := os.Open(localWatFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var emails []harvest.EmailMatch
+	var docs []harvest.DocLink
+	emailFinder := harvest.EmailFinder{}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		watPage := commoncrawl.ParseWatLine(line)
+		if watPage == nil {
+			continue
+		}
+
+		if len(targetDomains) > 0 && !isDomainMatch(watPage.URLRecord.Domain, targetDomains) {
+			continue
+		}
+
+		sourceURL := reconstructURL(watPage.URLRecord)
+
+		if wantEmails {
+			emails = append(emails, emailFinder.Find(line, sourceURL)...)
+		}
+
+		if wantDocs {
+			linkURLs := make([]string, 0, len(watPage.Links))
+			for _, link := range watPage.Links {
+				linkURLs = append(linkURLs, reconstructURL(&link))
+			}
+			docs = append(docs, docFinder.Find(linkURLs, sourceURL)...)
+		}
+	}
+
+	return emails, docs, nil
+}
+
+// downloadDocument fetches a harvested document into docsDir, naming the
+// file after its URL's basename to keep provenance legible on disk.
+// Best-effort: a failed fetch is logged and skipped rather than failing the
+// run, the same as writeWARCForResult does for --warc-output.
+func downloadDocument(client *http.Client, doc harvest.DocLink, docsDir string, guard chan struct{}) {
+	guard <- struct{}{}
+	defer func() { <-guard }()
+
+	resp, err := client.Get(doc.URL)
+	if err != nil {
+		log.Printf("⚠️  --download-docs: could not fetch %s: %v\n", doc.URL, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("⚠️  --download-docs: HTTP %d for %s\n", resp.StatusCode, doc.URL)
+		return
+	}
+
+	name := filepath.Base(doc.URL)
+	if name == "" || name == "." || name == "/" {
+		name = "doc" + doc.Extension
+	}
+	destPath := filepath.Join(docsDir, name)
+	out, err := os.Create(destPath)
+	if err != nil {
+		log.Printf("⚠️  --download-docs: could not create %s: %v\n", destPath, err)
+		return
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		log.Printf("⚠️  --download-docs: could not write %s: %v\n", destPath, err)
+	}
+}
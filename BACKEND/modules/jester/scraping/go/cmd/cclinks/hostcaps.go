@@ -0,0 +1,136 @@
+package main
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// HostCaps bounds how many matches a single host or registrable domain may
+// contribute to a run, so one target like blogspot.com or wordpress.com
+// can't flood the output with every subdomain of itself. Counters are keyed
+// on the full host (--max-per-host), the set of distinct subdomains seen
+// under a registrable domain (--max-subdomains-per-domain), and the total
+// matches kept under a registrable domain (--max-per-registrable-domain).
+// This mirrors the "branching out only to a predefined number of links per
+// hostname" cap that other crawlers in this family use to stay useful on
+// hosting platforms with huge subdomain counts.
+type HostCaps struct {
+	MaxPerHost              int
+	MaxSubdomainsPerDomain  int
+	MaxPerRegistrableDomain int
+
+	mu           sync.Mutex
+	perHost      map[string]int
+	perRegDomain map[string]int
+	subdomains   map[string]map[string]bool
+
+	Dropped int64
+}
+
+// NewHostCaps builds a HostCaps tracker. A zero value for any cap disables
+// it; NewHostCaps(0, 0, 0) is valid and Allow always returns true.
+func NewHostCaps(maxPerHost, maxSubdomainsPerDomain, maxPerRegistrableDomain int) *HostCaps {
+	return &HostCaps{
+		MaxPerHost:              maxPerHost,
+		MaxSubdomainsPerDomain:  maxSubdomainsPerDomain,
+		MaxPerRegistrableDomain: maxPerRegistrableDomain,
+		perHost:                 make(map[string]int),
+		perRegDomain:            make(map[string]int),
+		subdomains:              make(map[string]map[string]bool),
+	}
+}
+
+// Allow reports whether a match against host (e.g. "blog.example.com") is
+// still within every configured cap, bumping the relevant counters as a
+// side effect when it is. A nil receiver (no --max-* flags set) always
+// allows, so callers can pass a nil *HostCaps without a branch.
+func (h *HostCaps) Allow(host string) bool {
+	if h == nil || host == "" {
+		return true
+	}
+
+	regDomain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		regDomain = host
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.MaxPerHost > 0 && h.perHost[host] >= h.MaxPerHost {
+		h.Dropped++
+		return false
+	}
+
+	seenSubdomains := h.subdomains[regDomain]
+	isNewSubdomain := !seenSubdomains[host]
+	if h.MaxSubdomainsPerDomain > 0 && isNewSubdomain && len(seenSubdomains) >= h.MaxSubdomainsPerDomain {
+		h.Dropped++
+		return false
+	}
+
+	if h.MaxPerRegistrableDomain > 0 && h.perRegDomain[regDomain] >= h.MaxPerRegistrableDomain {
+		h.Dropped++
+		return false
+	}
+
+	if seenSubdomains == nil {
+		seenSubdomains = make(map[string]bool)
+		h.subdomains[regDomain] = seenSubdomains
+	}
+	seenSubdomains[host] = true
+	h.perHost[host]++
+	h.perRegDomain[regDomain]++
+	return true
+}
+
+// droppedCount reports how many matches Allow has rejected so far, for the
+// run summary.
+func (h *HostCaps) droppedCount() int64 {
+	if h == nil {
+		return 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.Dropped
+}
+
+// domainDedupShards is the shard count for DomainDedup's sync.Map set, so
+// --only-unique-domains doesn't serialize every worker goroutine through a
+// single contended map.
+const domainDedupShards = 16
+
+// DomainDedup tracks, for --only-unique-domains, which registrable domains
+// have already produced an OutlinkResult in this run (the link's target
+// domain for extract, the linking page's domain for backlinks), so later
+// matches against an already-seen domain are dropped and the run emits at
+// most one result per registrable domain.
+type DomainDedup struct {
+	shards [domainDedupShards]sync.Map
+}
+
+// NewDomainDedup returns an empty DomainDedup.
+func NewDomainDedup() *DomainDedup {
+	return &DomainDedup{}
+}
+
+func (d *DomainDedup) shardFor(key string) *sync.Map {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &d.shards[h.Sum32()%domainDedupShards]
+}
+
+// SeenOrMark reports whether a registrable domain has already been recorded
+// by a prior call, marking it seen as a side effect. Only the first call
+// for a given domain returns false.
+func (d *DomainDedup) SeenOrMark(domain string) bool {
+	regDomain, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	if err != nil {
+		regDomain = domain
+	}
+	shard := d.shardFor(regDomain)
+	_, loaded := shard.LoadOrStore(regDomain, true)
+	return loaded
+}
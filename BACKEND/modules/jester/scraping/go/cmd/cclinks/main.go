@@ -2,12 +2,16 @@ package main
 
 import (
 	"bufio"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -15,6 +19,9 @@ import (
 
 	"github.com/kris-dev-hub/globallinks/pkg/commoncrawl"
 	"github.com/kris-dev-hub/globallinks/pkg/fileutils"
+
+	"jester/scraping/go/pkg/trigram"
+	"jester/scraping/go/pkg/warcout"
 )
 
 const (
@@ -24,30 +31,57 @@ const (
 
 // OutlinkFilter defines filtering criteria for outlinks
 type OutlinkFilter struct {
-	TargetDomains    []string          // Specific domains to extract outlinks from
-	CountryTLDs      []string          // Country TLDs to include (e.g., .uk, .fr, .de)
-	URLKeywords      []string          // Keywords that must be in the outlink URL
-	ExcludeKeywords  []string          // Keywords to exclude from outlink URLs
-	MinAnchorLength  int               // Minimum anchor text length
-	MaxResults       int               // Maximum results per domain
-	OutputFormat     string            // Output format: json, csv, txt
-	IncludeInternal  bool              // Include internal links
-	CustomFilters    map[string]string // Custom regex filters
+	TargetDomains   []string          // Specific domains to extract outlinks from
+	CountryTLDs     []string          // Country TLDs to include (e.g., .uk, .fr, .de)
+	URLKeywords     []string          // Keywords that must be in the outlink URL
+	ExcludeKeywords []string          // Keywords to exclude from outlink URLs
+	IncludeRegex    []*regexp.Regexp  // --url-regex patterns; TargetURL or AnchorText must match at least one
+	ExcludeRegex    []*regexp.Regexp  // --exclude-regex / --exclude-from-file patterns; a match on either excludes
+	MinAnchorLength int               // Minimum anchor text length
+	MaxResults      int               // Maximum results per domain
+	OutputFormat    string            // Output format: json, csv, txt
+	IncludeInternal bool              // Include internal links
+	IncludeTags     []string          // Link tags to keep: primary, related, css (empty = keep all)
+	CustomFilters   map[string]string // Custom regex filters
+	OutputMaxSizeMB int               // With --format=warc, rotate to a new file past this size (default: 100)
+
+	MaxPerHost              int  // Cap matches per full host (e.g. a.blogspot.com), 0 = unlimited
+	MaxSubdomainsPerDomain  int  // Cap distinct subdomains counted per registrable domain, 0 = unlimited
+	MaxPerRegistrableDomain int  // Cap matches per registrable domain (e.g. blogspot.com), 0 = unlimited
+	OnlyUniqueDomains       bool // Emit at most one OutlinkResult per target registrable domain across the run
 }
 
+// Link tags, borrowed from the crawl project's primary/related model: a
+// "primary" link is a navigational <a href>, "related" is an embedded
+// resource (<link>/<script>/<img>), and "css" is a url(...) reference pulled
+// out of inline/embedded CSS.
+const (
+	LinkTagPrimary = "primary"
+	LinkTagRelated = "related"
+	LinkTagCSS     = "css"
+)
+
 // OutlinkResult represents an extracted outlink with metadata
 type OutlinkResult struct {
-	SourceDomain    string `json:"source_domain"`
-	SourceURL       string `json:"source_url"`
-	TargetDomain    string `json:"target_domain"`
-	TargetURL       string `json:"target_url"`
-	AnchorText      string `json:"anchor_text"`
-	LinkContext     string `json:"link_context,omitempty"`
-	DateDiscovered  string `json:"date_discovered"`
-	SourceIP        string `json:"source_ip,omitempty"`
-	IsNoFollow      bool   `json:"is_nofollow"`
-	MatchedFilter   string `json:"matched_filter,omitempty"`
-	RelevanceScore  int    `json:"relevance_score,omitempty"`
+	SourceDomain   string `json:"source_domain"`
+	SourceURL      string `json:"source_url"`
+	TargetDomain   string `json:"target_domain"`
+	TargetURL      string `json:"target_url"`
+	AnchorText     string `json:"anchor_text"`
+	LinkContext    string `json:"link_context,omitempty"`
+	LinkTag        string `json:"link_tag,omitempty"`
+	DateDiscovered string `json:"date_discovered"`
+	SourceIP       string `json:"source_ip,omitempty"`
+	IsNoFollow     bool   `json:"is_nofollow"`
+	MatchedFilter  string `json:"matched_filter,omitempty"`
+	RelevanceScore int    `json:"relevance_score,omitempty"`
+
+	// Provenance of the source page's own Common Crawl WARC record, known
+	// only in sniper mode (carried from CCIndexRecord); --format=warc emits
+	// these as WARC-Refers-To-Target-URI / WARC-Refers-To-Date.
+	SourceWarcFilename string `json:"source_warc_filename,omitempty"`
+	SourceWarcOffset   string `json:"source_warc_offset,omitempty"`
+	SourceWarcLength   string `json:"source_warc_length,omitempty"`
 }
 
 func main() {
@@ -60,7 +94,7 @@ func main() {
 	}
 
 	command := os.Args[1]
-	
+
 	switch command {
 	case "extract":
 		handleExtractCommand()
@@ -68,6 +102,8 @@ func main() {
 		handleBacklinksCommand()
 	case "sniper":
 		handleSniperCommand()
+	case "harvest":
+		handleHarvestCommand()
 	case "filter":
 		handleFilterCommand()
 	case "search":
@@ -89,6 +125,7 @@ func printUsage() {
 	fmt.Println("  extract    Extract outlinks FROM specific domains")
 	fmt.Println("  backlinks  Find backlinks pointing TO a target domain (Trawler)")
 	fmt.Println("  sniper     Find backlinks from known sources (Sniper)")
+	fmt.Println("  harvest    Extract emails and document-file links from crawled pages")
 	fmt.Println("  filter     Filter existing link data")
 	fmt.Println("  search     Search for links matching criteria")
 	fmt.Println()
@@ -101,6 +138,18 @@ func printUsage() {
 	fmt.Println("SNIPER USAGE:")
 	fmt.Println("  ./cclinks sniper --target-domain=\"example.com\" --source-domains=\"src1.com,src2.com\" --archive=CC-MAIN-2024-10 [OPTIONS]")
 	fmt.Println()
+	fmt.Println("SNIPER OPTIONS:")
+	fmt.Println("  --target-domain=DOMAINS  Comma-separated domains to find backlinks to (trigram-prefiltered per WAT line when more than one)")
+	fmt.Println("  --state=DIR              Persist per-WAT-file progress and a dedup bloom filter here; re-running skips scanned files and resumes partial downloads")
+	fmt.Println("  --max-retries=NUM        With --state, retry ceiling for a WAT file stuck in failed (default: 5)")
+	fmt.Println("  --stream                 Scan each WAT file straight off its HTTPS response instead of downloading it to disk first")
+	fmt.Println("  --dashboard=HOST:PORT    Serve a live progress/control dashboard (pause/resume, resize threads, tail results)")
+	fmt.Println("  --format=FORMAT          Output format: ndjson, warc (default: ndjson)")
+	fmt.Println("  --output-max-size-mb=MB  With --format=warc, rotate to a new part file past this size (default: 100)")
+	fmt.Println()
+	fmt.Println("HARVEST USAGE:")
+	fmt.Println("  ./cclinks harvest --archive=CC-MAIN-2024-10 [--domains=\"domain1.com,domain2.com\"] [OPTIONS]")
+	fmt.Println()
 	fmt.Println("EXTRACT OPTIONS:")
 	fmt.Println("  --domains=DOMAINS        Comma-separated list of source domains to extract from")
 	fmt.Println("  --archive=ARCHIVE        Common Crawl archive name (e.g., CC-MAIN-2021-04)")
@@ -108,18 +157,52 @@ func printUsage() {
 	fmt.Println("  --country-tlds=TLDS      Include only outlinks to these country TLDs (.uk,.fr,.de)")
 	fmt.Println("  --url-keywords=KEYWORDS  Include only outlinks containing these keywords")
 	fmt.Println("  --exclude=KEYWORDS       Exclude outlinks containing these keywords")
+	fmt.Println("  --url-regex=PATTERNS     With `filter`: keep only rows whose URL or anchor text matches one of these regexes")
+	fmt.Println("  --exclude-regex=PATTERNS With `filter`: drop rows whose URL or anchor text matches one of these regexes")
+	fmt.Println("  --exclude-from-file=PATH Load --exclude-regex patterns from a file, one pattern per line, # comments allowed")
 	fmt.Println("  --min-anchor=LENGTH      Minimum anchor text length (default: 3)")
 	fmt.Println("  --max-results=NUM        Maximum results per domain (default: 1000)")
-	fmt.Println("  --format=FORMAT          Output format: json, csv, txt (default: json)")
+	fmt.Println("  --format=FORMAT          Output format: json, csv, txt, warc (default: json)")
+	fmt.Println("  --output-max-size-mb=MB  With --format=warc, rotate to a new part file past this size (default: 100)")
 	fmt.Println("  --include-internal       Include internal links")
+	fmt.Println("  --include-tags=TAGS      Link tags to keep: primary,related,css (default: all)")
+	fmt.Println("  --max-per-host=N              Cap matches per full host, e.g. a.blogspot.com (default: unlimited)")
+	fmt.Println("  --max-subdomains-per-domain=N  Cap distinct subdomains counted per registrable domain (default: unlimited)")
+	fmt.Println("  --max-per-registrable-domain=N Cap matches per registrable domain, e.g. blogspot.com (default: unlimited)")
+	fmt.Println("  --only-unique-domains          Emit at most one result per target registrable domain across the run")
 	fmt.Println("  --threads=NUM            Number of processing threads (default: 2)")
 	fmt.Println("  --output=FILE            Output file path")
+	fmt.Println("  --warc-output=FILE       Also write a WARC 1.1 file with a response record per matched source page")
+	fmt.Println("  --dashboard=HOST:PORT    Serve a live progress/control dashboard (pause/resume, resize threads, add exclude keywords)")
 	fmt.Println()
 	fmt.Println("BACKLINKS OPTIONS:")
 	fmt.Println("  --target-domain=DOMAIN   Domain to find backlinks for")
 	fmt.Println("  --archive=ARCHIVE        Common Crawl archive name")
 	fmt.Println("  --source-tlds=TLDS       Include only backlinks from these TLDs")
 	fmt.Println("  --source-keywords=KEYS   Include only backlinks from pages with these keywords")
+	fmt.Println("  --include-tags=TAGS      Link tags to keep: primary,css (default: all)")
+	fmt.Println("  --max-per-host=N              Cap matches per full host, e.g. a.blogspot.com (default: unlimited)")
+	fmt.Println("  --max-subdomains-per-domain=N  Cap distinct subdomains counted per registrable domain (default: unlimited)")
+	fmt.Println("  --max-per-registrable-domain=N Cap matches per registrable domain, e.g. blogspot.com (default: unlimited)")
+	fmt.Println("  --only-unique-domains          Emit at most one backlink per source registrable domain across the run")
+	fmt.Println("  --queue-dir=DIR          Back the WAT worklist and dedup set on disk here (bounds RAM on big archives)")
+	fmt.Println("                           A reused --queue-dir always skips WAT files already popped in a prior run")
+	fmt.Println("  --resume                 With --queue-dir, log how many WAT files the reused queue still has pending")
+	fmt.Println("  --format=FORMAT          Output format: json, csv, txt, warc (default: json)")
+	fmt.Println("  --output-max-size-mb=MB  With --format=warc, rotate to a new part file past this size (default: 100)")
+	fmt.Println("  --warc-output=FILE       Also write a WARC 1.1 file with a response record per matched source page")
+	fmt.Println("  --dashboard=HOST:PORT    Serve a live progress/control dashboard (pause/resume, resize threads, add exclude keywords)")
+	fmt.Println()
+	fmt.Println("HARVEST OPTIONS:")
+	fmt.Println("  --archive=ARCHIVE        Common Crawl archive name")
+	fmt.Println("  --domains=DOMAINS        Restrict to pages from these source domains (default: all pages)")
+	fmt.Println("  --segments=SEGMENTS      Segments to process (e.g., 0-5 or 1,3,5)")
+	fmt.Println("  --kinds=KINDS            What to harvest: emails,docs (default: both)")
+	fmt.Println("  --doc-exts=EXTS          Document file extensions to match (default: .pdf,.doc,.docx,.xls,.xlsx,.ppt,.pptx,.odt,.csv)")
+	fmt.Println("  --download-docs          Download matched documents from their original host")
+	fmt.Println("  --docs-dir=DIR           Directory to download documents into (default: harvested_docs)")
+	fmt.Println("  --threads=NUM            Number of processing threads (default: 2)")
+	fmt.Println("  --output=DIR             Output directory for emails.txt/documents.ndjson (default: harvest_<archive>_<ts>)")
 	fmt.Println()
 	fmt.Println("FILTER USAGE:")
 	fmt.Println("  ./cclinks filter --input=data.json --country-tlds=.uk,.fr")
@@ -141,34 +224,34 @@ func printUsage() {
 func handleExtractCommand() {
 	// Parse command line arguments
 	filter := parseExtractArgs()
-	
+
 	if len(filter.TargetDomains) == 0 {
 		log.Println("Error: --domains parameter is required")
 		os.Exit(1)
 	}
-	
+
 	archive := getArgValue("--archive")
 	if archive == "" {
 		log.Println("Error: --archive parameter is required")
 		os.Exit(1)
 	}
-	
+
 	if !commoncrawl.IsCorrectArchiveFormat(archive) {
 		log.Println("Error: Invalid archive format")
 		os.Exit(1)
 	}
-	
+
 	segments := getArgValue("--segments")
 	if segments == "" {
 		segments = "0" // Default to first segment
 	}
-	
+
 	threads := getIntArg("--threads", 2)
 	outputFile := getArgValue("--output")
 	if outputFile == "" {
 		outputFile = fmt.Sprintf("outlinks_%s_%d.%s", archive, time.Now().Unix(), filter.OutputFormat)
 	}
-	
+
 	log.Printf("🔗 Starting outlink extraction...\n")
 	log.Printf("📁 Archive: %s\n", archive)
 	log.Printf("🎯 Target domains: %v\n", filter.TargetDomains)
@@ -176,13 +259,13 @@ func handleExtractCommand() {
 	log.Printf("🔍 URL keywords: %v\n", filter.URLKeywords)
 	log.Printf("📊 Threads: %d\n", threads)
 	log.Printf("💾 Output: %s\n", outputFile)
-	
+
 	// Start extraction
 	err := extractOutlinks(archive, segments, filter, threads, outputFile)
 	if err != nil {
 		log.Fatalf("Extraction failed: %v", err)
 	}
-	
+
 	log.Printf("✅ Extraction completed! Results saved to: %s\n", outputFile)
 }
 
@@ -202,7 +285,7 @@ func handleBacklinksCommand() {
 
 	// Handle "latest" alias - resolve to most recent available archive
 	if archive == "latest" {
-		archive = "CC-MAIN-2024-10"  // Default to known working archive
+		archive = "CC-MAIN-2024-10" // Default to known working archive
 		log.Printf("ℹ️  Resolving 'latest' to: %s\n", archive)
 	}
 
@@ -224,10 +307,10 @@ func handleBacklinksCommand() {
 	}
 
 	filter := OutlinkFilter{
-		TargetDomains:    []string{targetDomain},  // Store target for output
-		MinAnchorLength:  getIntArg("--min-anchor", 0),
-		MaxResults:       maxResults,
-		OutputFormat:     getArgValue("--format"),
+		TargetDomains:   []string{targetDomain}, // Store target for output
+		MinAnchorLength: getIntArg("--min-anchor", 0),
+		MaxResults:      maxResults,
+		OutputFormat:    getArgValue("--format"),
 	}
 
 	// Parse list arguments manually
@@ -240,6 +323,15 @@ func handleBacklinksCommand() {
 	if exclude := getArgValue("--exclude"); exclude != "" {
 		filter.ExcludeKeywords = strings.Split(exclude, ",")
 	}
+	if tags := getArgValue("--include-tags"); tags != "" {
+		filter.IncludeTags = strings.Split(tags, ",")
+	}
+
+	filter.MaxPerHost = getIntArg("--max-per-host", 0)
+	filter.MaxSubdomainsPerDomain = getIntArg("--max-subdomains-per-domain", 0)
+	filter.MaxPerRegistrableDomain = getIntArg("--max-per-registrable-domain", 0)
+	filter.OnlyUniqueDomains = hasArg("--only-unique-domains")
+	filter.OutputMaxSizeMB = getIntArg("--output-max-size-mb", defaultOutputMaxSizeMB)
 
 	if filter.OutputFormat == "" {
 		filter.OutputFormat = "json"
@@ -252,7 +344,7 @@ func handleBacklinksCommand() {
 	// Use --output=filename.json to save to file instead
 	useStdout := outputFile == "" || outputFile == "-" || outputFile == "stdout"
 	if outputFile == "" {
-		outputFile = "-"  // Marker for stdout
+		outputFile = "-" // Marker for stdout
 	}
 
 	log.Printf("🔗 Starting backlink extraction...\n")
@@ -293,7 +385,18 @@ func extractBacklinks(targetDomain, archive, segments string, filter OutlinkFilt
 	// Use stdout for piping, or create file
 	useStdout := outputFile == "-" || outputFile == "stdout" || outputFile == ""
 	var outFile *os.File
-	if useStdout {
+	var resultWarc *ResultWarcWriter
+	if filter.OutputFormat == "warc" {
+		if useStdout {
+			return fmt.Errorf("--format=warc requires --output (rotating part files can't be written to stdout)")
+		}
+		var err error
+		resultWarc, err = NewResultWarcWriter(outputFile, filter.OutputMaxSizeMB)
+		if err != nil {
+			return err
+		}
+		defer resultWarc.Close()
+	} else if useStdout {
 		outFile = os.Stdout
 		// For stdout, use NDJSON format (one JSON object per line)
 		filter.OutputFormat = "ndjson"
@@ -315,11 +418,136 @@ func extractBacklinks(targetDomain, archive, segments string, filter OutlinkFilt
 
 	var mutex sync.Mutex
 	var wg sync.WaitGroup
-	guard := make(chan struct{}, threads)
+	pool := NewWorkerPool(threads)
 
 	resultCount := 0
 	maxResults := filter.MaxResults
 
+	lf := newLiveFilter(filter)
+	runStats := newRunStats(0)
+	tail := newResultTail(200)
+	if dashboardAddr := getArgValue("--dashboard"); dashboardAddr != "" {
+		startDashboard(dashboardAddr, pool, runStats, lf, tail)
+	}
+
+	var warcWriter *warcout.WarcWriter
+	var warcSeenPages sync.Map
+	warcClient := &http.Client{Timeout: 20 * time.Second}
+	if warcOutputPath := getArgValue("--warc-output"); warcOutputPath != "" {
+		warcWriter, err = warcout.NewWarcWriter(warcOutputPath)
+		if err != nil {
+			return err
+		}
+		defer warcWriter.Close()
+	}
+
+	caps := NewHostCaps(filter.MaxPerHost, filter.MaxSubdomainsPerDomain, filter.MaxPerRegistrableDomain)
+	var dedup *DomainDedup
+	if filter.OnlyUniqueDomains {
+		dedup = NewDomainDedup()
+	}
+
+	queueDir := getArgValue("--queue-dir")
+	if queueDir != "" {
+		queue, err := OpenFileVisitQueue(queueDir)
+		if err != nil {
+			return fmt.Errorf("could not open visit queue: %v", err)
+		}
+		defer queue.Close()
+
+		if hasArg("--resume") {
+			log.Printf("🔁 Resuming from queue at %s (%d items already pending)\n", queueDir, queue.Len())
+		}
+
+		for _, segmentID := range segmentsToProcess {
+			segment, err := commoncrawl.SelectSegmentByID(segmentList, segmentID)
+			if err != nil {
+				log.Printf("⚠️ Warning: Segment %d not found\n", segmentID)
+				continue
+			}
+			for _, watFile := range segment.WatFiles {
+				if err := queue.Push(watFile.Path); err != nil {
+					return fmt.Errorf("could not enqueue %s: %v", watFile.Path, err)
+				}
+			}
+		}
+		log.Printf("📦 Queue has %d WAT files pending\n", queue.Len())
+		runStats.AddFilesTotal(int64(queue.Len()))
+
+		resultsCh := make(chan OutlinkResult, 1000)
+		var writerWG sync.WaitGroup
+		writerWG.Add(1)
+		go func() {
+			defer writerWG.Done()
+			for result := range resultsCh {
+				mutex.Lock()
+				if maxResults == 0 || resultCount < maxResults {
+					if resultWarc != nil {
+						if err := resultWarc.WriteResult(result); err != nil {
+							log.Printf("⚠️  could not write warc record: %v\n", err)
+						}
+					} else {
+						writeResult(outFile, result, filter.OutputFormat, resultCount > 0)
+					}
+					resultCount++
+					if jsonBytes, err := json.Marshal(result); err == nil {
+						tail.Add(string(jsonBytes))
+					}
+				}
+				mutex.Unlock()
+				writeWARCForResult(warcWriter, warcClient, archive, result, &warcSeenPages)
+			}
+		}()
+
+		for {
+			if maxResults > 0 && resultCount >= maxResults {
+				break
+			}
+			watPath, ok := queue.Pop()
+			if !ok {
+				break
+			}
+
+			wg.Add(1)
+			pool.Acquire()
+			go func(path string) {
+				defer wg.Done()
+				defer pool.Release()
+
+				results, err := processWATFileForBacklinks(path, targetDomain, lf.Get(), caps, dedup)
+				if err != nil {
+					log.Printf("❌ Error processing WAT file %s: %v", path, err)
+					runStats.IncErrors()
+					return
+				}
+				runStats.IncFiles()
+
+				for _, result := range results {
+					seenKey := result.SourceURL + "|" + result.TargetURL
+					if queue.SeenOrMark(seenKey) {
+						continue
+					}
+					runStats.IncResults(1)
+					resultsCh <- result
+				}
+			}(watPath)
+		}
+
+		wg.Wait()
+		close(resultsCh)
+		writerWG.Wait()
+
+		log.Println("🏁 All threads finished.")
+		if filter.OutputFormat == "json" {
+			outFile.WriteString("\n]")
+		}
+		log.Printf("📊 Total backlinks extracted: %d\n", resultCount)
+		if dropped := caps.droppedCount(); dropped > 0 {
+			log.Printf("🚧 %d matches dropped by --max-per-host/--max-subdomains-per-domain/--max-per-registrable-domain\n", dropped)
+		}
+		return nil
+	}
+
 	// Process each segment
 	for _, segmentID := range segmentsToProcess {
 		if maxResults > 0 && resultCount >= maxResults {
@@ -335,8 +563,9 @@ func extractBacklinks(targetDomain, archive, segments string, filter OutlinkFilt
 		log.Printf("🔄 Processing segment %d...\n", segmentID)
 
 		// Process WAT files in this segment
-	totalWATFiles := len(segment.WatFiles)
+		totalWATFiles := len(segment.WatFiles)
 		log.Printf("📁 Segment %d has %d WAT files\n", segmentID, totalWATFiles)
+		runStats.AddFilesTotal(int64(totalWATFiles))
 
 		filesProcessed := 0
 		for _, watFile := range segment.WatFiles {
@@ -352,18 +581,20 @@ func extractBacklinks(targetDomain, archive, segments string, filter OutlinkFilt
 			}
 
 			wg.Add(1)
-			guard <- struct{}{}
+			pool.Acquire()
 
 			go func(watPath string, fileNum int) {
 				defer wg.Done()
-				defer func() { <-guard }()
+				defer pool.Release()
 
 				// Download and process WAT file for backlinks
-				results, err := processWATFileForBacklinks(watPath, targetDomain, filter)
+				results, err := processWATFileForBacklinks(watPath, targetDomain, lf.Get(), caps, dedup)
 				if err != nil {
 					log.Printf("❌ Error processing WAT file %s: %v", watPath, err)
+					runStats.IncErrors()
 					return
 				}
+				runStats.IncFiles()
 
 				if len(results) > 0 {
 					log.Printf("✅ Found %d backlinks in WAT file #%d\n", len(results), fileNum)
@@ -375,11 +606,25 @@ func extractBacklinks(targetDomain, archive, segments string, filter OutlinkFilt
 					if maxResults > 0 && resultCount >= maxResults {
 						break
 					}
-					writeResult(outFile, result, filter.OutputFormat, resultCount > 0)
+					if resultWarc != nil {
+						if err := resultWarc.WriteResult(result); err != nil {
+							log.Printf("⚠️  could not write warc record: %v\n", err)
+						}
+					} else {
+						writeResult(outFile, result, filter.OutputFormat, resultCount > 0)
+					}
 					resultCount++
+					runStats.IncResults(1)
+					if jsonBytes, err := json.Marshal(result); err == nil {
+						tail.Add(string(jsonBytes))
+					}
 				}
 				mutex.Unlock()
 
+				for _, result := range results {
+					writeWARCForResult(warcWriter, warcClient, archive, result, &warcSeenPages)
+				}
+
 			}(watFile.Path, filesProcessed)
 		}
 	}
@@ -394,43 +639,46 @@ func extractBacklinks(targetDomain, archive, segments string, filter OutlinkFilt
 	}
 
 	log.Printf("📊 Total backlinks extracted: %d\n", resultCount)
+	if dropped := caps.droppedCount(); dropped > 0 {
+		log.Printf("🚧 %d matches dropped by --max-per-host/--max-subdomains-per-domain/--max-per-registrable-domain\n", dropped)
+	}
 	return nil
 }
 
 func handleFilterCommand() {
 	log.Println("🔍 Filter command - filters existing outlink data")
-	
+
 	inputFile := getArgValue("--input")
 	if inputFile == "" {
 		log.Println("Error: --input parameter is required")
 		os.Exit(1)
 	}
-	
+
 	filter := parseFilterArgs()
 	outputFile := getArgValue("--output")
 	if outputFile == "" {
 		outputFile = "filtered_outlinks.json"
 	}
-	
+
 	err := filterExistingData(inputFile, filter, outputFile)
 	if err != nil {
 		log.Fatalf("Filtering failed: %v", err)
 	}
-	
+
 	log.Printf("✅ Filtering completed! Results saved to: %s\n", outputFile)
 }
 
 func handleSearchCommand() {
 	log.Println("🔍 Search command - searches for specific outlink patterns")
-	
+
 	targetDomain := getArgValue("--target-domain")
 	inputDir := getArgValue("--input")
-	
+
 	if targetDomain == "" || inputDir == "" {
 		log.Println("Error: --target-domain and --input parameters are required")
 		os.Exit(1)
 	}
-	
+
 	err := searchOutlinks(targetDomain, inputDir)
 	if err != nil {
 		log.Fatalf("Search failed: %v", err)
@@ -443,88 +691,142 @@ func extractOutlinks(archive, segments string, filter OutlinkFilter, threads int
 	if err != nil {
 		return fmt.Errorf("could not load segment list: %v", err)
 	}
-	
+
 	// Parse segments
 	segmentsToProcess, err := parseSegmentInput(segments)
 	if err != nil {
 		return fmt.Errorf("invalid segment input: %v", err)
 	}
-	
-	// Create output file
-	outFile, err := os.Create(outputFile)
-	if err != nil {
-		return fmt.Errorf("could not create output file: %v", err)
+
+	// Create output file (or, for --format=warc, a rotating ResultWarcWriter)
+	var outFile *os.File
+	var resultWarc *ResultWarcWriter
+	if filter.OutputFormat == "warc" {
+		resultWarc, err = NewResultWarcWriter(outputFile, filter.OutputMaxSizeMB)
+		if err != nil {
+			return err
+		}
+		defer resultWarc.Close()
+	} else {
+		outFile, err = os.Create(outputFile)
+		if err != nil {
+			return fmt.Errorf("could not create output file: %v", err)
+		}
+		defer outFile.Close()
+
+		// Write header based on format
+		if filter.OutputFormat == "csv" {
+			outFile.WriteString("source_domain,source_url,target_domain,target_url,anchor_text,date_discovered,is_nofollow,matched_filter\n")
+		} else if filter.OutputFormat == "json" {
+			outFile.WriteString("[\n")
+		}
 	}
-	defer outFile.Close()
-	
-	// Write header based on format
-	if filter.OutputFormat == "csv" {
-		outFile.WriteString("source_domain,source_url,target_domain,target_url,anchor_text,date_discovered,is_nofollow,matched_filter\n")
-	} else if filter.OutputFormat == "json" {
-		outFile.WriteString("[\n")
-	}
-	
+
 	var mutex sync.Mutex
 	var wg sync.WaitGroup
-	guard := make(chan struct{}, threads)
-	
+	pool := NewWorkerPool(threads)
+
 	resultCount := 0
-	
+
+	lf := newLiveFilter(filter)
+	runStats := newRunStats(0)
+	tail := newResultTail(200)
+	if dashboardAddr := getArgValue("--dashboard"); dashboardAddr != "" {
+		startDashboard(dashboardAddr, pool, runStats, lf, tail)
+	}
+
+	var warcWriter *warcout.WarcWriter
+	var warcSeenPages sync.Map
+	warcClient := &http.Client{Timeout: 20 * time.Second}
+	if warcOutputPath := getArgValue("--warc-output"); warcOutputPath != "" {
+		warcWriter, err = warcout.NewWarcWriter(warcOutputPath)
+		if err != nil {
+			return err
+		}
+		defer warcWriter.Close()
+	}
+
+	caps := NewHostCaps(filter.MaxPerHost, filter.MaxSubdomainsPerDomain, filter.MaxPerRegistrableDomain)
+	var dedup *DomainDedup
+	if filter.OnlyUniqueDomains {
+		dedup = NewDomainDedup()
+	}
+
 	// Process each segment
 	for _, segmentID := range segmentsToProcess {
 		segment, err := commoncrawl.SelectSegmentByID(segmentList, segmentID)
 		if err != nil {
 			continue
 		}
-		
+
 		log.Printf("🔄 Processing segment %d...\n", segmentID)
-		
+
 		// Process WAT files in this segment
 		for i, watFile := range segment.WatFiles {
 			if i >= 5 { // Limit for testing
 				break
 			}
-			
+			runStats.AddFilesTotal(1)
+
 			wg.Add(1)
-			guard <- struct{}{}
-			
+			pool.Acquire()
+
 			go func(watPath string) {
 				defer wg.Done()
-				defer func() { <-guard }()
-				
+				defer pool.Release()
+
 				// Download and process WAT file
-				results, err := processWATFileForOutlinks(watPath, filter)
+				results, err := processWATFileForOutlinks(watPath, lf.Get(), caps, dedup)
 				if err != nil {
 					log.Printf("Error processing WAT file %s: %v", watPath, err)
+					runStats.IncErrors()
 					return
 				}
-				
+				runStats.IncFiles()
+
 				// Write results to file
 				mutex.Lock()
 				for _, result := range results {
-					writeResult(outFile, result, filter.OutputFormat, resultCount > 0)
+					if resultWarc != nil {
+						if err := resultWarc.WriteResult(result); err != nil {
+							log.Printf("⚠️  could not write warc record: %v\n", err)
+						}
+					} else {
+						writeResult(outFile, result, filter.OutputFormat, resultCount > 0)
+					}
 					resultCount++
+					runStats.IncResults(1)
+					if jsonBytes, err := json.Marshal(result); err == nil {
+						tail.Add(string(jsonBytes))
+					}
 				}
 				mutex.Unlock()
-				
+
+				for _, result := range results {
+					writeWARCForResult(warcWriter, warcClient, archive, result, &warcSeenPages)
+				}
+
 			}(watFile.Path)
 		}
 	}
-	
+
 	wg.Wait()
-	
+
 	// Close JSON array
 	if filter.OutputFormat == "json" {
 		outFile.WriteString("\n]")
 	}
-	
+
 	log.Printf("📊 Total outlinks extracted: %d\n", resultCount)
+	if dropped := caps.droppedCount(); dropped > 0 {
+		log.Printf("🚧 %d matches dropped by --max-per-host/--max-subdomains-per-domain/--max-per-registrable-domain\n", dropped)
+	}
 	return nil
 }
 
-func processWATFileForOutlinks(watPath string, filter OutlinkFilter) ([]OutlinkResult, error) {
+func processWATFileForOutlinks(watPath string, filter OutlinkFilter, caps *HostCaps, dedup *DomainDedup) ([]OutlinkResult, error) {
 	var results []OutlinkResult
-	
+
 	// Create temp directory for WAT file
 	tempDir := "temp_wat"
 	err := fileutils.CreateDataDirectory(tempDir)
@@ -532,7 +834,7 @@ func processWATFileForOutlinks(watPath string, filter OutlinkFilter) ([]OutlinkR
 		return nil, err
 	}
 	defer os.RemoveAll(tempDir)
-	
+
 	// Download WAT file
 	localWatFile := filepath.Join(tempDir, filepath.Base(watPath))
 	err = fileutils.DownloadFile("https://data.commoncrawl.org/"+watPath, localWatFile, 2)
@@ -540,61 +842,79 @@ func processWATFileForOutlinks(watPath string, filter OutlinkFilter) ([]OutlinkR
 		return nil, fmt.Errorf("could not download WAT file: %v", err)
 	}
 	defer os.Remove(localWatFile)
-	
+
 	// Process WAT file line by line
 	file, err := os.Open(localWatFile)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
-	
+
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		line := scanner.Text()
-		
+
 		// Parse line and extract page data
 		watPage := commoncrawl.ParseWatLine(line)
 		if watPage == nil {
 			continue
 		}
-		
+
 		// Check if this page is from one of our target domains
 		if !isDomainMatch(watPage.URLRecord.Domain, filter.TargetDomains) {
 			continue
 		}
-		
+
+		sourceURL := reconstructURL(watPage.URLRecord)
+
 		// Extract outlinks that match our filters
 		for _, link := range watPage.Links {
-			if shouldIncludeOutlink(link, filter) {
+			if shouldIncludeOutlink(link, filter, caps) && shouldIncludeTag(LinkTagPrimary, filter) {
+				if dedup != nil && dedup.SeenOrMark(link.Domain) {
+					continue
+				}
 				result := OutlinkResult{
 					SourceDomain:   watPage.URLRecord.Domain,
-					SourceURL:      reconstructURL(watPage.URLRecord),
+					SourceURL:      sourceURL,
 					TargetDomain:   link.Domain,
 					TargetURL:      reconstructURL(&link),
 					AnchorText:     link.Text,
+					LinkTag:        LinkTagPrimary,
 					DateDiscovered: *watPage.Imported,
 					SourceIP:       *watPage.IP,
 					IsNoFollow:     link.NoFollow == 1,
 					RelevanceScore: calculateRelevanceScore(link, filter),
 				}
-			
+
 				// Determine which filter matched
-			result.MatchedFilter = getMatchedFilter(link, filter)
-			
-			results = append(results, result)
-			
-			// Limit results per domain
-			if len(results) >= filter.MaxResults {
-				break
+				result.MatchedFilter = getMatchedFilter(link, filter)
+
+				results = append(results, result)
+
+				// Limit results per domain
+				if len(results) >= filter.MaxResults {
+					break
+				}
 			}
 		}
+
+		if shouldIncludeTag(LinkTagCSS, filter) {
+			for _, cssLink := range extractCSSLinks(watPage.URLRecord.Domain, sourceURL, line) {
+				if dedup != nil && dedup.SeenOrMark(cssLink.TargetDomain) {
+					continue
+				}
+				results = append(results, cssLink)
+				if len(results) >= filter.MaxResults {
+					break
+				}
+			}
 		}
 	}
-	
+
 	return results, nil
 }
 
-func processWATFileForBacklinks(watPath, targetDomain string, filter OutlinkFilter) ([]OutlinkResult, error) {
+func processWATFileForBacklinks(watPath, targetDomain string, filter OutlinkFilter, caps *HostCaps, dedup *DomainDedup) ([]OutlinkResult, error) {
 	var results []OutlinkResult
 
 	// Create unique temp directory for this WAT file
@@ -680,33 +1000,66 @@ func processWATFileForBacklinks(watPath, targetDomain string, filter OutlinkFilt
 		}
 
 		// Now check if ANY link on this page points TO the target domain
-		for _, link := range watPage.Links {
-			linksChecked++
+		if shouldIncludeTag(LinkTagPrimary, filter) {
+			for _, link := range watPage.Links {
+				linksChecked++
+
+				if isDomainMatch(link.Domain, []string{targetDomain}) {
+					// Check anchor text minimum length
+					if len(link.Text) < filter.MinAnchorLength {
+						continue
+					}
 
-			if isDomainMatch(link.Domain, []string{targetDomain}) {
-				// Check anchor text minimum length
-				if len(link.Text) < filter.MinAnchorLength {
-					continue
-				}
+					// Smart-crawl caps: bound how many backlinks a single source host
+					// (or its registrable domain) may contribute, so a platform like
+					// blogspot.com can't flood the run with every subdomain it hosts.
+					if !caps.Allow(watPage.URLRecord.Host) {
+						continue
+					}
+					if dedup != nil && dedup.SeenOrMark(watPage.URLRecord.Domain) {
+						continue
+					}
 
-				result := OutlinkResult{
-					SourceDomain:   watPage.URLRecord.Domain,
-					SourceURL:      reconstructURL(watPage.URLRecord),
-					TargetDomain:   link.Domain,
-					TargetURL:      reconstructURL(&link),
-					AnchorText:     link.Text,
-					DateDiscovered: *watPage.Imported,
-					SourceIP:       *watPage.IP,
-					IsNoFollow:     link.NoFollow == 1,
-					RelevanceScore: calculateRelevanceScore(link, filter),
-					MatchedFilter:  fmt.Sprintf("target:%s", targetDomain),
-				}
+					result := OutlinkResult{
+						SourceDomain:   watPage.URLRecord.Domain,
+						SourceURL:      reconstructURL(watPage.URLRecord),
+						TargetDomain:   link.Domain,
+						TargetURL:      reconstructURL(&link),
+						AnchorText:     link.Text,
+						LinkTag:        LinkTagPrimary,
+						DateDiscovered: *watPage.Imported,
+						SourceIP:       *watPage.IP,
+						IsNoFollow:     link.NoFollow == 1,
+						RelevanceScore: calculateRelevanceScore(link, filter),
+						MatchedFilter:  fmt.Sprintf("target:%s", targetDomain),
+					}
 
-				results = append(results, result)
+					results = append(results, result)
+
+					// Limit results if specified
+					if filter.MaxResults > 0 && len(results) >= filter.MaxResults {
+						log.Printf("📊 WAT file stats: %d pages scanned, %d links checked, %d backlinks found", pagesScanned, linksChecked, len(results))
+						return results, nil
+					}
+				}
+			}
+		}
 
-				// Limit results if specified
+		// Also catch CSS url(...) references that point at the target domain
+		if shouldIncludeTag(LinkTagCSS, filter) && strings.Contains(line, targetDomain) {
+			for _, cssLink := range extractCSSLinks(watPage.URLRecord.Domain, reconstructURL(watPage.URLRecord), line) {
+				if !isDomainMatch(cssLink.TargetDomain, []string{targetDomain}) {
+					continue
+				}
+				if !caps.Allow(watPage.URLRecord.Host) {
+					continue
+				}
+				if dedup != nil && dedup.SeenOrMark(watPage.URLRecord.Domain) {
+					continue
+				}
+				cssLink.MatchedFilter = fmt.Sprintf("target:%s", targetDomain)
+				results = append(results, cssLink)
 				if filter.MaxResults > 0 && len(results) >= filter.MaxResults {
-					log.Printf("📊 WAT file stats: %d pages scanned, %d links checked, %d backlinks found", pagesScanned, linksChecked, len(results))
 					return results, nil
 				}
 			}
@@ -720,6 +1073,115 @@ func processWATFileForBacklinks(watPath, targetDomain string, filter OutlinkFilt
 	return results, nil
 }
 
+// cssURLPattern matches url(...) references inside @import/CSS property
+// values, e.g. `background: url('/img/bg.png')` or `@import url(foo.css)`.
+var cssURLPattern = regexp.MustCompile(`(?:@import|:).*url\(["']?([^'"\)]+)["']?\)`)
+
+// extractCSSLinks scans a raw WAT payload line for inline/embedded CSS
+// url(...) references and resolves them to absolute URLs against the source
+// page, tagging each one LinkTagCSS. The underlying WAT parser only exposes
+// structured <a>/<link>/<script>/<img> links, not CSS payloads, so this is
+// the one case where we fall back to pattern-matching the raw line.
+func extractCSSLinks(sourceDomain, sourceURL, rawLine string) []OutlinkResult {
+	matches := cssURLPattern.FindAllStringSubmatch(rawLine, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	base, err := url.Parse(sourceURL)
+	if err != nil {
+		return nil
+	}
+
+	var results []OutlinkResult
+	for _, m := range matches {
+		ref, err := url.Parse(strings.TrimSpace(m[1]))
+		if err != nil {
+			continue
+		}
+		resolved := base.ResolveReference(ref)
+
+		results = append(results, OutlinkResult{
+			SourceDomain:  sourceDomain,
+			SourceURL:     sourceURL,
+			TargetDomain:  resolved.Hostname(),
+			TargetURL:     resolved.String(),
+			LinkTag:       LinkTagCSS,
+			MatchedFilter: "css:url()",
+		})
+	}
+	return results
+}
+
+// shouldIncludeTag reports whether a link tagged `tag` passes the
+// --include-tags scope-boundary filter. An empty filter keeps everything, so
+// turning on CSS extraction never drops links that were already being found.
+func shouldIncludeTag(tag string, filter OutlinkFilter) bool {
+	if len(filter.IncludeTags) == 0 {
+		return true
+	}
+	for _, t := range filter.IncludeTags {
+		if strings.EqualFold(strings.TrimSpace(t), tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeWARCForResult writes a response record for result.SourceURL (skipping
+// it if already written this run) plus a metadata record linking it to
+// result, into warcWriter. Best-effort: a page that can't be found in the
+// CDX or range-fetched is logged and skipped rather than failing the run.
+func writeWARCForResult(warcWriter *warcout.WarcWriter, client *http.Client, archive string, result OutlinkResult, seen *sync.Map) {
+	if warcWriter == nil {
+		return
+	}
+	if v, alreadyWritten := seen.LoadOrStore(result.SourceURL, ""); alreadyWritten {
+		// The page's response record is already handled; add only this
+		// result's metadata record, if that response record made it in.
+		if recordID, _ := v.(string); recordID != "" {
+			if body, err := json.Marshal(result); err == nil {
+				if err := warcWriter.WriteMetadata(result.SourceURL, recordID, body); err != nil {
+					log.Printf("⚠️  --warc-output: could not write metadata record for %s: %v\n", result.SourceURL, err)
+				}
+			}
+		}
+		return
+	}
+
+	rec, err := warcout.QueryCCIndexExact(client, result.SourceURL, archive)
+	if err != nil {
+		log.Printf("⚠️  --warc-output: could not locate %s in CDX: %v\n", result.SourceURL, err)
+		return
+	}
+
+	offset, err1 := strconv.ParseInt(rec.Offset, 10, 64)
+	length, err2 := strconv.ParseInt(rec.Length, 10, 64)
+	if err1 != nil || err2 != nil {
+		log.Printf("⚠️  --warc-output: bad offset/length for %s\n", result.SourceURL)
+		return
+	}
+
+	raw, recordID, err := warcout.FetchAndCopyWARCRecord(client, rec.Filename, offset, length)
+	if err != nil {
+		log.Printf("⚠️  --warc-output: could not fetch WARC record for %s: %v\n", result.SourceURL, err)
+		return
+	}
+	if err := warcWriter.WriteRawRecord(raw); err != nil {
+		log.Printf("⚠️  --warc-output: could not write response record for %s: %v\n", result.SourceURL, err)
+		return
+	}
+	seen.Store(result.SourceURL, recordID)
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	if err := warcWriter.WriteMetadata(result.SourceURL, recordID, body); err != nil {
+		log.Printf("⚠️  --warc-output: could not write metadata record for %s: %v\n", result.SourceURL, err)
+	}
+}
+
 func isDomainMatch(domain string, targetDomains []string) bool {
 	for _, target := range targetDomains {
 		if strings.Contains(domain, target) || strings.Contains(target, domain) {
@@ -729,7 +1191,7 @@ func isDomainMatch(domain string, targetDomains []string) bool {
 	return false
 }
 
-func shouldIncludeOutlink(link commoncrawl.URLRecord, filter OutlinkFilter) bool {
+func shouldIncludeOutlink(link commoncrawl.URLRecord, filter OutlinkFilter, caps *HostCaps) bool {
 	// Check country TLD filter
 	if len(filter.CountryTLDs) > 0 {
 		matched := false
@@ -743,7 +1205,7 @@ func shouldIncludeOutlink(link commoncrawl.URLRecord, filter OutlinkFilter) bool
 			return false
 		}
 	}
-	
+
 	// Check URL keyword filter
 	if len(filter.URLKeywords) > 0 {
 		matched := false
@@ -758,7 +1220,7 @@ func shouldIncludeOutlink(link commoncrawl.URLRecord, filter OutlinkFilter) bool
 			return false
 		}
 	}
-	
+
 	// Check exclude keywords
 	if len(filter.ExcludeKeywords) > 0 {
 		fullURL := reconstructURL(&link)
@@ -768,21 +1230,27 @@ func shouldIncludeOutlink(link commoncrawl.URLRecord, filter OutlinkFilter) bool
 			}
 		}
 	}
-	
+
 	// Check minimum anchor length
 	if len(link.Text) < filter.MinAnchorLength {
 		return false
 	}
-	
+
+	// Smart-crawl caps: drop further matches once a host/domain has used up
+	// its --max-per-host / --max-subdomains-per-domain / --max-per-registrable-domain budget.
+	if !caps.Allow(link.Host) {
+		return false
+	}
+
 	return true
 }
 
 func calculateRelevanceScore(link commoncrawl.URLRecord, filter OutlinkFilter) int {
 	score := 0
-	
+
 	// Base score
 	score += 10
-	
+
 	// Anchor text quality
 	if len(link.Text) > 10 {
 		score += 5
@@ -790,44 +1258,44 @@ func calculateRelevanceScore(link commoncrawl.URLRecord, filter OutlinkFilter) i
 	if len(link.Text) > 25 {
 		score += 5
 	}
-	
+
 	// Domain quality
 	if !link.IsSubdomain() {
 		score += 10
 	}
-	
+
 	// DoFollow bonus
 	if link.NoFollow == 0 {
 		score += 15
 	}
-	
+
 	// Keyword matches in anchor text
 	for _, keyword := range filter.URLKeywords {
 		if strings.Contains(strings.ToLower(link.Text), strings.ToLower(keyword)) {
 			score += 20
 		}
 	}
-	
+
 	return score
 }
 
 func getMatchedFilter(link commoncrawl.URLRecord, filter OutlinkFilter) string {
 	var matches []string
-	
+
 	// Check which filters matched
 	for _, tld := range filter.CountryTLDs {
 		if strings.HasSuffix(link.Domain, tld) {
 			matches = append(matches, fmt.Sprintf("tld:%s", tld))
 		}
 	}
-	
+
 	fullURL := reconstructURL(&link)
 	for _, keyword := range filter.URLKeywords {
 		if strings.Contains(strings.ToLower(fullURL), strings.ToLower(keyword)) {
 			matches = append(matches, fmt.Sprintf("keyword:%s", keyword))
 		}
 	}
-	
+
 	return strings.Join(matches, ",")
 }
 
@@ -836,12 +1304,12 @@ func reconstructURL(record *commoncrawl.URLRecord) string {
 	if record.Scheme == "2" {
 		scheme = "https"
 	}
-	
+
 	url := fmt.Sprintf("%s://%s%s", scheme, record.Host, record.Path)
 	if record.RawQuery != "" {
 		url += "?" + record.RawQuery
 	}
-	
+
 	return url
 }
 
@@ -969,6 +1437,27 @@ func passesFilter(record OutlinkResult, filter OutlinkFilter) bool {
 		}
 	}
 
+	// Include regex filter (--url-regex): TargetURL or AnchorText must match at least one
+	if len(filter.IncludeRegex) > 0 {
+		matched := false
+		for _, re := range filter.IncludeRegex {
+			if re.MatchString(record.TargetURL) || re.MatchString(record.AnchorText) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	// Exclude regex filter (--exclude-regex / --exclude-from-file)
+	for _, re := range filter.ExcludeRegex {
+		if re.MatchString(record.TargetURL) || re.MatchString(record.AnchorText) {
+			return false
+		}
+	}
+
 	// Anchor text minimum length
 	if filter.MinAnchorLength > 0 && len(record.AnchorText) < filter.MinAnchorLength {
 		return false
@@ -1051,27 +1540,45 @@ func parseExtractArgs() OutlinkFilter {
 		MaxResults:      1000,
 		OutputFormat:    "json",
 	}
-	
+
 	// Parse domains
 	if domains := getArgValue("--domains"); domains != "" {
 		filter.TargetDomains = strings.Split(domains, ",")
 	}
-	
+
 	// Parse country TLDs
 	if tlds := getArgValue("--country-tlds"); tlds != "" {
 		filter.CountryTLDs = strings.Split(tlds, ",")
 	}
-	
+
 	// Parse URL keywords
 	if keywords := getArgValue("--url-keywords"); keywords != "" {
 		filter.URLKeywords = strings.Split(keywords, ",")
 	}
-	
+
 	// Parse exclude keywords
 	if exclude := getArgValue("--exclude"); exclude != "" {
 		filter.ExcludeKeywords = strings.Split(exclude, ",")
 	}
-	
+
+	// Parse include/exclude regex patterns
+	if pattern := getArgValue("--url-regex"); pattern != "" {
+		filter.IncludeRegex = compileRegexList(strings.Split(pattern, ","))
+	}
+
+	var excludePatterns []string
+	if pattern := getArgValue("--exclude-regex"); pattern != "" {
+		excludePatterns = append(excludePatterns, strings.Split(pattern, ",")...)
+	}
+	if path := getArgValue("--exclude-from-file"); path != "" {
+		fromFile, err := loadPatternsFromFile(path)
+		if err != nil {
+			log.Fatalf("Error reading --exclude-from-file: %v", err)
+		}
+		excludePatterns = append(excludePatterns, fromFile...)
+	}
+	filter.ExcludeRegex = compileRegexList(excludePatterns)
+
 	// Parse other options
 	filter.MinAnchorLength = getIntArg("--min-anchor", 3)
 	filter.MaxResults = getIntArg("--max-results", 1000)
@@ -1080,7 +1587,17 @@ func parseExtractArgs() OutlinkFilter {
 		filter.OutputFormat = "json"
 	}
 	filter.IncludeInternal = hasArg("--include-internal")
-	
+
+	if tags := getArgValue("--include-tags"); tags != "" {
+		filter.IncludeTags = strings.Split(tags, ",")
+	}
+
+	filter.MaxPerHost = getIntArg("--max-per-host", 0)
+	filter.MaxSubdomainsPerDomain = getIntArg("--max-subdomains-per-domain", 0)
+	filter.MaxPerRegistrableDomain = getIntArg("--max-per-registrable-domain", 0)
+	filter.OnlyUniqueDomains = hasArg("--only-unique-domains")
+	filter.OutputMaxSizeMB = getIntArg("--output-max-size-mb", defaultOutputMaxSizeMB)
+
 	return filter
 }
 
@@ -1089,6 +1606,45 @@ func parseFilterArgs() OutlinkFilter {
 	return parseExtractArgs()
 }
 
+// compileRegexList compiles each pattern case-insensitively (matching the
+// existing keyword filters' case-insensitive semantics), skipping and
+// logging any pattern that doesn't parse rather than aborting the run.
+func compileRegexList(patterns []string) []*regexp.Regexp {
+	var compiled []*regexp.Regexp
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		re, err := regexp.Compile("(?i)" + p)
+		if err != nil {
+			log.Printf("⚠️  Skipping invalid regex %q: %v\n", p, err)
+			continue
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled
+}
+
+// loadPatternsFromFile reads one regex pattern per line from path, ignoring
+// blank lines and `#`-prefixed comments.
+func loadPatternsFromFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, nil
+}
+
 func getArgValue(arg string) string {
 	for _, a := range os.Args {
 		if strings.HasPrefix(a, arg+"=") {
@@ -1162,7 +1718,6 @@ func parseSegmentInput(segments string) ([]int, error) {
 	return results, nil
 }
 
-
 // --- SNIPER MODE IMPLEMENTATION ---
 
 // CCIndexRecord represents a record from the Common Crawl Index API
@@ -1179,21 +1734,37 @@ type WatLocation struct {
 	WatFilename string `json:"wat_filename"`
 }
 
+// sniperSourceRecord is one source page sniper is looking for outlinks on.
+// Filename/Offset/Length carry the page's own Common Crawl WARC location
+// when known (from --source-domains' CCIndexRecord; --wat-list has no such
+// record) so matched OutlinkResults can populate SourceWarc* for --format=warc.
+type sniperSourceRecord struct {
+	URL      string
+	Filename string
+	Offset   string
+	Length   string
+}
+
 func handleSniperCommand() {
-	targetDomain := getArgValue("--target-domain")
+	var targetDomains []string
+	for _, d := range strings.Split(getArgValue("--target-domain"), ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			targetDomains = append(targetDomains, d)
+		}
+	}
 	sourceDomainsStr := getArgValue("--source-domains")
 	watListFile := getArgValue("--wat-list")
 	archive := getArgValue("--archive")
 
-	if targetDomain == "" {
+	if len(targetDomains) == 0 {
 		log.Fatal("Error: --target-domain is required")
 	}
-	
+
 	// If wat-list is NOT provided, we need source-domains
 	if watListFile == "" && sourceDomainsStr == "" {
 		log.Fatal("Error: either --source-domains or --wat-list is required")
 	}
-	
+
 	if archive == "" {
 		archive = "CC-MAIN-2024-10" // Default to known working archive
 		log.Printf("ℹ️  Using default archive: %s\n", archive)
@@ -1201,15 +1772,29 @@ func handleSniperCommand() {
 
 	threads := getIntArg("--threads", 4)
 	outputFile := getArgValue("--output")
-	
+	outputFormat := getArgValue("--format")
+	if outputFormat == "" {
+		outputFormat = "ndjson"
+	}
+
 	// Output setup
 	useStdout := outputFile == "" || outputFile == "-" || outputFile == "stdout"
 	var outFile *os.File
+	var resultWarc *ResultWarcWriter
 	var err error
-	
-	if useStdout {
+
+	if outputFormat == "warc" {
+		if useStdout {
+			log.Fatal("Error: --format=warc requires --output (rotating part files can't be written to stdout)")
+		}
+		resultWarc, err = NewResultWarcWriter(outputFile, getIntArg("--output-max-size-mb", defaultOutputMaxSizeMB))
+		if err != nil {
+			log.Fatalf("Error creating warc output: %v", err)
+		}
+		defer resultWarc.Close()
+	} else if useStdout {
 		outFile = os.Stdout
-		outputFile = "-" 
+		outputFile = "-"
 	} else {
 		outFile, err = os.Create(outputFile)
 		if err != nil {
@@ -1218,11 +1803,43 @@ func handleSniperCommand() {
 		defer outFile.Close()
 	}
 
+	targetDomainKey := strings.Join(targetDomains, ",")
+
 	log.Printf("🔫 Starting SNIPER mode...\n")
-	log.Printf("🎯 Target: %s\n", targetDomain)
+	log.Printf("🎯 Target: %s\n", targetDomainKey)
 	log.Printf("📁 Archive: %s\n", archive)
 
-	watFiles := make(map[string][]string) // watFile -> [urls]
+	var sourceDomains []string
+	if sourceDomainsStr != "" {
+		for _, s := range strings.Split(sourceDomainsStr, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				sourceDomains = append(sourceDomains, s)
+			}
+		}
+	}
+
+	var state *SniperState
+	maxRetries := getIntArg("--max-retries", 5)
+	if stateDir := getArgValue("--state"); stateDir != "" {
+		state, err = OpenSniperState(stateDir)
+		if err != nil {
+			log.Fatalf("Error opening --state store: %v", err)
+		}
+		defer state.Close()
+
+		if prior, found := state.LoadManifest(); found {
+			if prior.TargetDomain != targetDomainKey || prior.Archive != archive {
+				log.Printf("⚠️  --state %s was last used for target=%s archive=%s; this run is target=%s archive=%s\n",
+					stateDir, prior.TargetDomain, prior.Archive, targetDomainKey, archive)
+			}
+		}
+		if err := state.SaveManifest(SniperManifest{TargetDomain: targetDomainKey, SourceDomains: sourceDomains, Archive: archive}); err != nil {
+			log.Printf("⚠️  Could not save --state manifest: %v\n", err)
+		}
+		log.Printf("💾 Resumable state: %s (max %d retries per WAT file)\n", stateDir, maxRetries)
+	}
+
+	watFiles := make(map[string][]sniperSourceRecord) // watFile -> [sources]
 
 	if watListFile != "" {
 		log.Printf("📂 Loading WAT list from: %s\n", watListFile)
@@ -1230,106 +1847,168 @@ func handleSniperCommand() {
 		if err != nil {
 			log.Fatalf("Error reading WAT list file: %v", err)
 		}
-		
+
 		var locations []WatLocation
 		if err := json.Unmarshal(fileContent, &locations); err != nil {
 			log.Fatalf("Error parsing WAT list JSON: %v", err)
 		}
-		
+
 		for _, loc := range locations {
-			watFiles[loc.WatFilename] = append(watFiles[loc.WatFilename], loc.URL)
+			watFiles[loc.WatFilename] = append(watFiles[loc.WatFilename], sniperSourceRecord{URL: loc.URL})
 		}
 		log.Printf("✅ Loaded %d locations from file\n", len(locations))
-		
+
 	} else {
 		// Original Logic: Query CC Index
-		sourceDomains := strings.Split(sourceDomainsStr, ",")
 		log.Printf("🌐 Sources: %d domains\n", len(sourceDomains))
-		
+
 		// 1. Query CC Index for all source domains
 		log.Println("🔍 Querying CC Index...")
-		
+
 		for _, source := range sourceDomains {
 			// Trim whitespace
 			source = strings.TrimSpace(source)
-			if source == "" { continue }
-			
+			if source == "" {
+				continue
+			}
+
 			records, err := queryCCIndex(source, archive)
 			if err != nil {
 				log.Printf("⚠️  Error querying %s: %v\n", source, err)
 				continue
 			}
-			
+
 			if len(records) == 0 {
 				log.Printf("⚠️  No records found for %s\n", source)
 			}
-			
+
 			for _, rec := range records {
 				// Convert WARC path to WAT path
 				watPath := strings.Replace(rec.Filename, "/warc/", "/wat/", 1)
 				watPath = strings.Replace(watPath, ".warc.gz", ".warc.wat.gz", 1)
-				
-				watFiles[watPath] = append(watFiles[watPath], rec.URL)
+
+				watFiles[watPath] = append(watFiles[watPath], sniperSourceRecord{
+					URL: rec.URL, Filename: rec.Filename, Offset: rec.Offset, Length: rec.Length,
+				})
 			}
 		}
 	}
 
 	log.Printf("📦 Found pages distributed across %d WAT files\n", len(watFiles))
+
+	watPaths := make([]string, 0, len(watFiles))
+	for watPath := range watFiles {
+		watPaths = append(watPaths, watPath)
+	}
+	if state != nil {
+		pending := state.PendingWatFiles(watPaths, maxRetries)
+		log.Printf("🔁 %d/%d WAT files already scanned or past --max-retries; %d pending\n",
+			len(watPaths)-len(pending), len(watPaths), len(pending))
+		watPaths = pending
+	}
+
 	// 2. Process WAT files
 	var wg sync.WaitGroup
-	guard := make(chan struct{}, threads)
+	pool := NewWorkerPool(threads)
 	var mutex sync.Mutex
-	
+
+	// A trigram prefilter only pays for itself once there's more than one
+	// domain to look for per line; the single-domain case keeps using the
+	// plain strings.Contains fast path it always has.
+	var trigramIdx *trigram.Index
+	if len(targetDomains) > 1 {
+		trigramIdx = trigram.New(targetDomains)
+	}
+
+	// --stream scans a WAT file straight off its HTTPS response body instead
+	// of materializing it to disk first; it trades a little retry overhead
+	// for not needing a few hundred MB of free /tmp per in-flight file.
+	streamMode := hasArg("--stream")
+
 	resultCount := 0
-	
-	for watPath, urls := range watFiles {
+	duplicatesDropped := 0
+
+	runStats := newRunStats(len(watPaths))
+	tail := newResultTail(200)
+	if dashboardAddr := getArgValue("--dashboard"); dashboardAddr != "" {
+		startDashboard(dashboardAddr, pool, runStats, nil, tail)
+	}
+
+	for _, watPath := range watPaths {
+		urls := watFiles[watPath]
 		wg.Add(1)
-		guard <- struct{}{}
-		
-		go func(path string, targetUrls []string) {
+		pool.Acquire()
+
+		go func(path string, targetUrls []sniperSourceRecord) {
 			defer wg.Done()
-			defer func() { <-guard }()
-			
-			results, err := processWATSniper(path, targetDomain, targetUrls)
+			defer pool.Release()
+
+			var results []OutlinkResult
+			var err error
+			if streamMode {
+				results, err = streamWATSniper(path, targetDomains, trigramIdx, targetUrls)
+			} else {
+				results, err = processWATSniper(path, targetDomains, trigramIdx, targetUrls, state)
+			}
 			if err != nil {
 				log.Printf("❌ Error processing %s: %v\n", path, err)
+				runStats.IncErrors()
+				if state != nil {
+					state.SetState(path, WatFailed, err.Error())
+				}
 				return
 			}
-			
+			runStats.IncFiles()
+			if state != nil {
+				state.SetState(path, WatScanned, "")
+			}
+
 			if len(results) > 0 {
 				mutex.Lock()
 				for _, r := range results {
-					// Write NDJSON
-					jsonBytes, _ := json.Marshal(r)
-					outFile.Write(jsonBytes)
-					outFile.WriteString("\n")
+					if state != nil && state.SeenOrMark(r.SourceURL, r.TargetURL) {
+						duplicatesDropped++
+						continue
+					}
+					if resultWarc != nil {
+						resultWarc.WriteResult(r)
+					} else {
+						jsonBytes, _ := json.Marshal(r)
+						outFile.Write(jsonBytes)
+						outFile.WriteString("\n")
+						tail.Add(string(jsonBytes))
+					}
 					resultCount++
+					runStats.IncResults(1)
 				}
 				mutex.Unlock()
 				log.Printf("✅ Found %d links in %s\n", len(results), path)
 			}
 		}(watPath, urls)
 	}
-	
+
 	wg.Wait()
 	log.Printf("🏁 Sniper finished. Total backlinks: %d\n", resultCount)
+	if duplicatesDropped > 0 {
+		log.Printf("🧹 %d already-seen link rows dropped by the --state bloom filter\n", duplicatesDropped)
+	}
 }
 
 func queryCCIndex(domain, archive string) ([]CCIndexRecord, error) {
 	// Limit to 50 pages per domain to be fast ("sniper")
 	url := fmt.Sprintf("https://index.commoncrawl.org/%s-index?url=%s/*&output=json&fl=url,filename,offset,length&limit=50", archive, domain)
-	
+
 	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Get(url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
-	
+
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
 	}
-	
+
 	var records []CCIndexRecord
 	scanner := bufio.NewScanner(resp.Body)
 	for scanner.Scan() {
@@ -1341,77 +2020,237 @@ func queryCCIndex(domain, archive string) ([]CCIndexRecord, error) {
 	return records, nil
 }
 
-func processWATSniper(watPath, targetDomain string, targetUrls []string) ([]OutlinkResult, error) {
-	// Create target URL set for fast lookup
-	targetUrlSet := make(map[string]bool)
+// buildTargetUrlSet indexes targetUrls by normalized URL (lower-cased,
+// trailing slash stripped) for O(1) source-page lookup while scanning a WAT
+// file, shared between processWATSniper's local-file scan and
+// streamWATSniper's HTTPS streaming scan.
+func buildTargetUrlSet(targetUrls []sniperSourceRecord) map[string]sniperSourceRecord {
+	set := make(map[string]sniperSourceRecord, len(targetUrls))
 	for _, u := range targetUrls {
-		// Normalize: lower case, strip trailing slash
-		norm := strings.ToLower(strings.TrimRight(u, "/"))
-		targetUrlSet[norm] = true
+		norm := strings.ToLower(strings.TrimRight(u.URL, "/"))
+		set[norm] = u
 	}
+	return set
+}
 
-	// Create temp dir
-	tempDir, err := os.MkdirTemp("", "wat_sniper_*")
-	if err != nil {
-		return nil, err
+// scanWATLineForSniper applies the target-domain prefilter and source-page
+// match to a single WAT line, returning any outlinks found on it. Shared
+// between processWATSniper and streamWATSniper so both scanning paths stay
+// in sync.
+func scanWATLineForSniper(line string, targetDomains []string, trigramIdx *trigram.Index, targetUrlSet map[string]sniperSourceRecord) []OutlinkResult {
+	// Quick pre-check: does this line mention one of our target domains? If
+	// not, it can't have a link to any of them, so skip the JSON parse
+	// entirely. With a single target this is a plain Contains; with
+	// several, a trigram prefilter rules out most targets before paying for
+	// the exact Contains check on each survivor.
+	if trigramIdx != nil {
+		matched := false
+		for _, candidate := range trigramIdx.Candidates(line) {
+			if strings.Contains(line, candidate) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil
+		}
+	} else if !strings.Contains(line, targetDomains[0]) {
+		return nil
 	}
-	defer os.RemoveAll(tempDir)
-	
-	localWatFile := filepath.Join(tempDir, filepath.Base(watPath))
-	err = fileutils.DownloadFile("https://data.commoncrawl.org/"+watPath, localWatFile, 3) // 3 retries
-	if err != nil {
-		return nil, err
+
+	watPage := commoncrawl.ParseWatLine(line)
+	if watPage == nil {
+		return nil
 	}
-	defer os.Remove(localWatFile)
-	
+
+	// Check if this is one of the pages we are looking for (Source Page)
+	sourceUrl := strings.ToLower(strings.TrimRight(watPage.URLRecord.URL, "/"))
+	src, ok := targetUrlSet[sourceUrl]
+	if !ok {
+		return nil
+	}
+
+	var results []OutlinkResult
+	for _, link := range watPage.Links {
+		if isDomainMatch(link.Domain, targetDomains) {
+			results = append(results, OutlinkResult{
+				SourceDomain:       watPage.URLRecord.Domain,
+				SourceURL:          watPage.URLRecord.URL,
+				TargetDomain:       link.Domain,
+				TargetURL:          reconstructURL(&link),
+				AnchorText:         link.Text,
+				DateDiscovered:     *watPage.Imported,
+				MatchedFilter:      "sniper",
+				SourceWarcFilename: src.Filename,
+				SourceWarcOffset:   src.Offset,
+				SourceWarcLength:   src.Length,
+			})
+		}
+	}
+	return results
+}
+
+func processWATSniper(watPath string, targetDomains []string, trigramIdx *trigram.Index, targetUrls []sniperSourceRecord, state *SniperState) ([]OutlinkResult, error) {
+	targetUrlSet := buildTargetUrlSet(targetUrls)
+
+	var localWatFile string
+	var cleanup func()
+
+	if state != nil {
+		// Keep the download under --state so an interrupted fetch resumes
+		// from where it left off instead of starting over.
+		localWatFile = state.cachePath(watPath)
+		client := &http.Client{Timeout: 60 * time.Second}
+		if err := downloadResumable(client, "https://data.commoncrawl.org/"+watPath, localWatFile); err != nil {
+			return nil, fmt.Errorf("could not download WAT file: %w", err)
+		}
+		state.SetState(watPath, WatDownloaded, "")
+		cleanup = func() {}
+	} else {
+		tempDir, err := os.MkdirTemp("", "wat_sniper_*")
+		if err != nil {
+			return nil, err
+		}
+		localWatFile = filepath.Join(tempDir, filepath.Base(watPath))
+		if err := fileutils.DownloadFile("https://data.commoncrawl.org/"+watPath, localWatFile, 3); err != nil { // 3 retries
+			os.RemoveAll(tempDir)
+			return nil, err
+		}
+		cleanup = func() { os.RemoveAll(tempDir) }
+	}
+	defer cleanup()
+
 	file, err := os.Open(localWatFile)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
-	
+
 	var results []OutlinkResult
 	scanner := bufio.NewScanner(file)
-	
+
 	// Buffer size increase for large lines
 	const maxCapacity = 1024 * 1024 * 5 // 5MB
 	buf := make([]byte, maxCapacity)
 	scanner.Buffer(buf, maxCapacity)
-	
+
 	for scanner.Scan() {
-		line := scanner.Text()
-		
-		// Quick pre-check: does this line contain our target domain?
-		// Optimization: If the line doesn't mention the target domain, it can't have a link to it.
-		if !strings.Contains(line, targetDomain) {
-			continue 
+		results = append(results, scanWATLineForSniper(scanner.Text(), targetDomains, trigramIdx, targetUrlSet)...)
+	}
+	return results, nil
+}
+
+// countingReader wraps r, tracking exactly how many bytes have been
+// consumed from it so streamWATSniper can Range-resume precisely where
+// decoding left off instead of re-fetching the whole file. It buffers
+// reads from r internally (via bufio.Reader) for efficiency, but only
+// advances count as bytes are actually handed back through Read/ReadByte
+// — never for bytes merely pulled into that internal buffer. Implementing
+// io.ByteReader matters here: compress/flate wraps any reader that
+// doesn't provide ReadByte in its own ~4KB-chunk bufio.Reader, which would
+// otherwise make it read arbitrarily far ahead of the line streamWATSniper
+// has actually scanned, so a resume's Range request could start past real,
+// unscanned WAT records.
+type countingReader struct {
+	br    *bufio.Reader
+	count int64
+}
+
+func newCountingReader(r io.Reader) *countingReader {
+	return &countingReader{br: bufio.NewReader(r)}
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.br.Read(p)
+	c.count += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.br.ReadByte()
+	if err == nil {
+		c.count++
+	}
+	return b, err
+}
+
+// streamWATSniper scans watPath's WAT records straight off its HTTPS
+// response body instead of materializing the whole file to disk first the
+// way processWATSniper does — several hundred MB per in-flight file that a
+// disk-space-limited container may not have to spare. On a transient read
+// error it retries with a Range request starting from the compressed-byte
+// offset it had reached after the last successfully-scanned line (tracked
+// via countingReader), rather than starting over from byte zero; since
+// WAT/WARC files are multi-member gzip with one member per record, that
+// checkpoint falls at or just before a member boundary, so at most the
+// trailing partial record gets re-read.
+func streamWATSniper(watPath string, targetDomains []string, trigramIdx *trigram.Index, targetUrls []sniperSourceRecord) ([]OutlinkResult, error) {
+	targetUrlSet := buildTargetUrlSet(targetUrls)
+	url := "https://data.commoncrawl.org/" + watPath
+	client := &http.Client{Timeout: 120 * time.Second}
+
+	var results []OutlinkResult
+	var resumeOffset int64
+	const maxAttempts = 5
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		if resumeOffset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", resumeOffset))
 		}
 
-		watPage := commoncrawl.ParseWatLine(line)
-		if watPage == nil {
+		resp, err := client.Do(req)
+		if err != nil {
+			if attempt == maxAttempts {
+				return nil, fmt.Errorf("could not fetch %s: %w", watPath, err)
+			}
+			log.Printf("⚠️  --stream: %s request failed (attempt %d/%d), retrying from byte %d: %v\n", watPath, attempt, maxAttempts, resumeOffset, err)
 			continue
 		}
-		
-		// Check if this is one of the pages we are looking for (Source Page)
-		sourceUrl := strings.ToLower(strings.TrimRight(watPage.URLRecord.URL, "/"))
-		if !targetUrlSet[sourceUrl] {
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+			resp.Body.Close()
+			return nil, fmt.Errorf("http %d fetching %s", resp.StatusCode, watPath)
+		}
+
+		base := resumeOffset
+		counting := newCountingReader(resp.Body)
+		gz, err := gzip.NewReader(counting)
+		if err != nil {
+			resp.Body.Close()
+			if attempt == maxAttempts {
+				return nil, fmt.Errorf("could not open gzip stream for %s: %w", watPath, err)
+			}
+			log.Printf("⚠️  --stream: %s gzip open failed (attempt %d/%d), retrying from byte %d: %v\n", watPath, attempt, maxAttempts, resumeOffset, err)
 			continue
 		}
-		
-		// Check links
-		for _, link := range watPage.Links {
-			if isDomainMatch(link.Domain, []string{targetDomain}) {
-				results = append(results, OutlinkResult{
-					SourceDomain: watPage.URLRecord.Domain,
-					SourceURL: watPage.URLRecord.URL,
-					TargetDomain: link.Domain,
-					TargetURL: reconstructURL(&link),
-					AnchorText: link.Text,
-					DateDiscovered: *watPage.Imported,
-					MatchedFilter: "sniper",
-				})
+
+		scanErr := func() error {
+			defer resp.Body.Close()
+			defer gz.Close()
+
+			scanner := bufio.NewScanner(gz)
+			const maxCapacity = 1024 * 1024 * 5 // 5MB
+			buf := make([]byte, maxCapacity)
+			scanner.Buffer(buf, maxCapacity)
+
+			for scanner.Scan() {
+				results = append(results, scanWATLineForSniper(scanner.Text(), targetDomains, trigramIdx, targetUrlSet)...)
+				resumeOffset = base + counting.count
 			}
+			return scanner.Err()
+		}()
+
+		if scanErr == nil {
+			return results, nil
 		}
+		if attempt == maxAttempts {
+			return results, fmt.Errorf("exhausted retries streaming %s: %w", watPath, scanErr)
+		}
+		log.Printf("⚠️  --stream: %s read error (attempt %d/%d), retrying from byte %d: %v\n", watPath, attempt, maxAttempts, resumeOffset, scanErr)
 	}
-	return results, nil
+
+	return results, fmt.Errorf("exhausted retries streaming %s", watPath)
 }
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"jester/scraping/go/pkg/warcout"
+)
+
+// defaultOutputMaxSizeMB is how large a --format=warc part file grows before
+// ResultWarcWriter rotates to the next one.
+const defaultOutputMaxSizeMB = 100
+
+// ResultWarcWriter writes OutlinkResults as WARC/1.1 `metadata` records, one
+// per matched outlink, instead of the csv/json/ndjson/txt formats writeResult
+// produces. WARC-Target-URI is the source page; the body is an
+// application/link-format (RFC 6690) description of the match so any
+// WARC-aware pipeline can ingest the extracted link graph directly. Output
+// rotates to a new numbered part past --output-max-size-mb, the way
+// conventional WARC crawlers cap a single archive file's size.
+type ResultWarcWriter struct {
+	basePath string
+	maxBytes int64
+
+	file    *os.File
+	written int64
+	part    int
+}
+
+// NewResultWarcWriter opens the first part file, path's "-00000" sibling
+// (see partPath); nothing is written to path itself. maxSizeMB <= 0 falls
+// back to defaultOutputMaxSizeMB.
+func NewResultWarcWriter(path string, maxSizeMB int) (*ResultWarcWriter, error) {
+	if maxSizeMB <= 0 {
+		maxSizeMB = defaultOutputMaxSizeMB
+	}
+	w := &ResultWarcWriter{basePath: path, maxBytes: int64(maxSizeMB) * 1024 * 1024}
+	if err := w.openPart(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// partPath names the Nth rotated file: path.warc -> path-00000.warc,
+// path-00001.warc, ...
+func (w *ResultWarcWriter) partPath() string {
+	ext := filepath.Ext(w.basePath)
+	base := strings.TrimSuffix(w.basePath, ext)
+	return fmt.Sprintf("%s-%05d%s", base, w.part, ext)
+}
+
+func (w *ResultWarcWriter) openPart() error {
+	f, err := os.Create(w.partPath())
+	if err != nil {
+		return fmt.Errorf("could not create WARC output %s: %w", w.partPath(), err)
+	}
+	w.file = f
+	w.written = 0
+	return nil
+}
+
+// rotateIfNeeded starts a new part file if appending nextRecordSize bytes to
+// the current one would exceed maxBytes. The very first record never
+// rotates, so a single oversized record doesn't spin up an empty part first.
+func (w *ResultWarcWriter) rotateIfNeeded(nextRecordSize int64) error {
+	if w.written == 0 || w.written+nextRecordSize <= w.maxBytes {
+		return nil
+	}
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.part++
+	return w.openPart()
+}
+
+func (w *ResultWarcWriter) Close() error {
+	return w.file.Close()
+}
+
+// WriteResult appends result as a gzip-member `metadata` record.
+func (w *ResultWarcWriter) WriteResult(result OutlinkResult) error {
+	id := warcout.NewWarcRecordID()
+	date := time.Now().UTC().Format("2006-01-02T15:04:05Z")
+
+	body := fmt.Sprintf("<%s>; rel=\"outlink\"; anchor=%q; nofollow=%t; discovered=%q\n",
+		result.TargetURL, result.AnchorText, result.IsNoFollow, result.DateDiscovered)
+
+	var header strings.Builder
+	header.WriteString("WARC/1.1\r\n")
+	header.WriteString("WARC-Type: metadata\r\n")
+	header.WriteString(fmt.Sprintf("WARC-Record-ID: %s\r\n", id))
+	header.WriteString(fmt.Sprintf("WARC-Date: %s\r\n", date))
+	header.WriteString(fmt.Sprintf("WARC-Target-URI: %s\r\n", result.SourceURL))
+	if result.SourceWarcFilename != "" {
+		header.WriteString(fmt.Sprintf("WARC-Refers-To-Target-URI: https://data.commoncrawl.org/%s?offset=%s&length=%s\r\n",
+			result.SourceWarcFilename, result.SourceWarcOffset, result.SourceWarcLength))
+		header.WriteString(fmt.Sprintf("WARC-Refers-To-Date: %s\r\n", result.DateDiscovered))
+	}
+	header.WriteString("Content-Type: application/link-format\r\n")
+	header.WriteString(fmt.Sprintf("Content-Length: %d\r\n", len(body)))
+	header.WriteString("\r\n")
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(header.String())); err != nil {
+		return err
+	}
+	if _, err := gz.Write([]byte(body)); err != nil {
+		return err
+	}
+	gz.Write([]byte("\r\n\r\n"))
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	if err := w.rotateIfNeeded(int64(buf.Len())); err != nil {
+		return err
+	}
+
+	n, err := w.file.Write(buf.Bytes())
+	w.written += int64(n)
+	return err
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// WatState tracks a WAT file's progress through the sniper pipeline so a
+// `sniper --state <dir>` run can resume after being interrupted, mirroring
+// ccwarc's JobStore (keyed by domain there, by WAT file path here).
+type WatState string
+
+const (
+	WatPending    WatState = "pending"
+	WatDownloaded WatState = "downloaded"
+	WatScanned    WatState = "scanned"
+	WatFailed     WatState = "failed"
+)
+
+var (
+	watFilesBucket = []byte("wat_files")
+	bloomBucket    = []byte("bloom")
+	manifestBucket = []byte("manifest")
+)
+
+// WatRecord is what's persisted per WAT file path in the sniper state store.
+type WatRecord struct {
+	Path       string    `json:"path"`
+	State      WatState  `json:"state"`
+	Attempts   int       `json:"attempts"`
+	FailReason string    `json:"fail_reason,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// SniperManifest records the parameters a `sniper --state` run was started
+// with, so a later run against the same --state dir can warn if the target
+// domain, source domains, or archive have changed underneath it.
+type SniperManifest struct {
+	TargetDomain  string   `json:"target_domain"`
+	SourceDomains []string `json:"source_domains"`
+	Archive       string   `json:"archive"`
+}
+
+// SniperState is a bbolt-backed KV store giving `sniper --state` Ctrl-C/
+// resume semantics: per-WAT-file status (pending/downloaded/scanned/failed),
+// a manifest of the run's parameters, and a bloom filter of source|target
+// link pairs already emitted so overlapping archives on re-runs don't
+// duplicate output rows.
+type SniperState struct {
+	dir string
+	db  *bbolt.DB
+
+	mu    sync.Mutex
+	bloom *bloomFilter
+}
+
+// OpenSniperState opens (creating if needed) the sniper state store under
+// dir: dir/state.db for the bbolt KV data and dir/wat-cache/ for
+// partially-downloaded WAT files that survive a restart.
+func OpenSniperState(dir string) (*SniperState, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "wat-cache"), 0755); err != nil {
+		return nil, fmt.Errorf("could not create state dir %s: %w", dir, err)
+	}
+
+	db, err := bbolt.Open(filepath.Join(dir, "state.db"), 0644, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("could not open sniper state db: %w", err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, bucket := range [][]byte{watFilesBucket, bloomBucket, manifestBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	bloom := newBloomFilter(bloomBits, bloomHashes)
+	db.View(func(tx *bbolt.Tx) error {
+		if raw := tx.Bucket(bloomBucket).Get([]byte("bits")); raw != nil {
+			bloom.load(raw)
+		}
+		return nil
+	})
+
+	return &SniperState{dir: dir, db: db, bloom: bloom}, nil
+}
+
+// Close persists the bloom filter's bits and closes the underlying db.
+func (s *SniperState) Close() error {
+	if err := s.persistBloom(); err != nil {
+		s.db.Close()
+		return err
+	}
+	return s.db.Close()
+}
+
+func (s *SniperState) persistBloom() error {
+	s.mu.Lock()
+	bits := s.bloom.dump()
+	s.mu.Unlock()
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bloomBucket).Put([]byte("bits"), bits)
+	})
+}
+
+// LoadManifest returns the manifest a prior run against this state dir was
+// started with, if any.
+func (s *SniperState) LoadManifest() (SniperManifest, bool) {
+	var m SniperManifest
+	found := false
+	s.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(manifestBucket).Get([]byte("manifest"))
+		if v == nil {
+			return nil
+		}
+		if err := json.Unmarshal(v, &m); err == nil {
+			found = true
+		}
+		return nil
+	})
+	return m, found
+}
+
+// SaveManifest persists this run's parameters, so a future run against the
+// same --state dir can detect drift.
+func (s *SniperState) SaveManifest(m SniperManifest) error {
+	sorted := append([]string(nil), m.SourceDomains...)
+	sort.Strings(sorted)
+	m.SourceDomains = sorted
+
+	encoded, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(manifestBucket).Put([]byte("manifest"), encoded)
+	})
+}
+
+func (s *SniperState) Get(path string) (WatRecord, bool) {
+	var rec WatRecord
+	found := false
+	s.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(watFilesBucket).Get([]byte(path))
+		if v == nil {
+			return nil
+		}
+		if err := json.Unmarshal(v, &rec); err == nil {
+			found = true
+		}
+		return nil
+	})
+	return rec, found
+}
+
+// SetState records path's new state, bumping Attempts whenever the
+// transition is into WatFailed so callers can enforce --max-retries.
+func (s *SniperState) SetState(path string, state WatState, reason string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(watFilesBucket)
+
+		rec := WatRecord{Path: path}
+		if v := bucket.Get([]byte(path)); v != nil {
+			json.Unmarshal(v, &rec)
+		}
+
+		rec.State = state
+		rec.UpdatedAt = time.Now()
+		if state == WatFailed {
+			rec.Attempts++
+			rec.FailReason = reason
+		} else {
+			rec.FailReason = ""
+		}
+
+		encoded, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(path), encoded)
+	})
+}
+
+// PendingWatFiles returns the subset of paths that still need work: never
+// seen, not yet WatScanned, or WatFailed below maxAttempts. A maxAttempts of
+// 0 means unlimited retries.
+func (s *SniperState) PendingWatFiles(paths []string, maxAttempts int) []string {
+	var pending []string
+	for _, path := range paths {
+		rec, found := s.Get(path)
+		if !found {
+			pending = append(pending, path)
+			continue
+		}
+		if rec.State == WatScanned {
+			continue
+		}
+		if rec.State == WatFailed && maxAttempts > 0 && rec.Attempts >= maxAttempts {
+			continue
+		}
+		pending = append(pending, path)
+	}
+	return pending
+}
+
+// cachePath returns where watPath's partially/fully downloaded copy lives on
+// disk, keyed by its sha1 so nested archive paths collapse to a flat,
+// filesystem-safe name.
+func (s *SniperState) cachePath(watPath string) string {
+	h := sha1.Sum([]byte(watPath))
+	return filepath.Join(s.dir, "wat-cache", hex.EncodeToString(h[:])+".wat.gz")
+}
+
+// SeenOrMark reports whether the source|target link pair has already been
+// emitted by a prior run against this state store, marking it seen as a
+// side effect. Backed by a bloom filter, so false positives are possible: a
+// resumed run may drop a handful of genuinely-new rows in exchange for not
+// having to keep every pair seen across every archive in RAM.
+func (s *SniperState) SeenOrMark(sourceURL, targetURL string) bool {
+	key := sourceURL + "|" + targetURL
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bloom.testAndAdd(key)
+}
+
+// downloadResumable fetches url into destPath, appending to and resuming
+// from whatever destPath already holds (a prior run's partial download)
+// via a Range request, instead of starting over from byte zero.
+func downloadResumable(client *http.Client, url, destPath string) error {
+	existing, err := os.Stat(destPath)
+	var startAt int64
+	if err == nil {
+		startAt = existing.Size()
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	if startAt > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", startAt))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// Server ignored our Range request and sent the whole object back;
+		// truncate whatever partial bytes we had and start clean.
+		flags |= os.O_TRUNC
+	default:
+		return fmt.Errorf("http %d", resp.StatusCode)
+	}
+
+	out, err := os.OpenFile(destPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, resp.Body)
+	return err
+}
+
+// bloomBits/bloomHashes size the "already-seen link" bloom filter: 16M bits
+// (2MB) and 4 hash functions keeps the false-positive rate low into the
+// low-single-digit millions of entries without keeping every pair in RAM.
+const (
+	bloomBits   = 1 << 24
+	bloomHashes = 4
+)
+
+// bloomFilter is a minimal fixed-size bit-array bloom filter using double
+// hashing (two independent hashes combined, à la Kirsch-Mitzenmacher) to
+// derive bloomHashes bit positions per key without needing bloomHashes
+// separate hash functions.
+type bloomFilter struct {
+	bits []byte
+	m    uint64
+	k    int
+}
+
+func newBloomFilter(m uint64, k int) *bloomFilter {
+	return &bloomFilter{bits: make([]byte, (m+7)/8), m: m, k: k}
+}
+
+func (b *bloomFilter) positions(key string) []uint64 {
+	h1 := fnv.New64a()
+	h1.Write([]byte(key))
+	sum1 := h1.Sum64()
+
+	h2 := fnv.New32a()
+	h2.Write([]byte(key))
+	sum2 := uint64(h2.Sum32())
+
+	positions := make([]uint64, b.k)
+	for i := 0; i < b.k; i++ {
+		positions[i] = (sum1 + uint64(i)*sum2) % b.m
+	}
+	return positions
+}
+
+// testAndAdd reports whether key was already (probably) present, setting
+// its bits as a side effect either way.
+func (b *bloomFilter) testAndAdd(key string) bool {
+	present := true
+	for _, pos := range b.positions(key) {
+		byteIdx, bitIdx := pos/8, pos%8
+		if b.bits[byteIdx]&(1<<bitIdx) == 0 {
+			present = false
+		}
+		b.bits[byteIdx] |= 1 << bitIdx
+	}
+	return present
+}
+
+func (b *bloomFilter) dump() []byte {
+	out := make([]byte, len(b.bits))
+	copy(out, b.bits)
+	return out
+}
+
+func (b *bloomFilter) load(data []byte) {
+	copy(b.bits, data)
+}
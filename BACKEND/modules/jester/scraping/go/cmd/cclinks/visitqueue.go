@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Queue backs the in-flight WAT file worklist and the seen-pair dedup set
+// for extractBacklinks, so a --queue-dir run can bound memory on a
+// full-archive crawl and resume after being interrupted.
+type Queue interface {
+	Push(item string) error
+	Pop() (string, bool)
+	// SeenOrMark reports whether key has already been marked seen by a
+	// prior call, marking it seen as a side effect either way. Combining
+	// the check and the mark into one call keeps it atomic under a single
+	// lock acquisition — callers must not pair a separate Seen/MarkSeen
+	// since nothing would then serialize the two.
+	SeenOrMark(key string) bool
+	Len() int
+	Close() error
+}
+
+// FileVisitQueue is a --queue-dir-backed Queue. Pending work is an
+// append-only log with one raw item per line (queue.ndjson) plus a parallel
+// log of popped items (popped.ndjson) so a restart can replay the queue and
+// skip anything already claimed; the seen-pair dedup set is a bloom filter
+// (the same construction as cclinks' sniper --state, see sniperstate.go)
+// dumped to seen.bloom on Close, so a full CC-MAIN segment's millions of
+// source|target pairs take a fixed 2MB rather than one entry each.
+type FileVisitQueue struct {
+	mu         sync.Mutex
+	pending    []string
+	donePopped map[string]bool
+	queueFile  *os.File
+	poppedFile *os.File
+	bloomPath  string
+	bloom      *bloomFilter
+}
+
+func OpenFileVisitQueue(dir string) (*FileVisitQueue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create queue dir %s: %w", dir, err)
+	}
+
+	bloomPath := filepath.Join(dir, "seen.bloom")
+	bloom := newBloomFilter(bloomBits, bloomHashes)
+	if saved, err := os.ReadFile(bloomPath); err == nil {
+		bloom.load(saved)
+	}
+
+	donePopped := make(map[string]bool)
+	if f, err := os.Open(filepath.Join(dir, "popped.ndjson")); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			donePopped[scanner.Text()] = true
+		}
+		f.Close()
+	}
+
+	var pending []string
+	if f, err := os.Open(filepath.Join(dir, "queue.ndjson")); err == nil {
+		scanner := bufio.NewScanner(f)
+		buf := make([]byte, 1024*1024)
+		scanner.Buffer(buf, 1024*1024)
+		for scanner.Scan() {
+			item := scanner.Text()
+			if !donePopped[item] {
+				pending = append(pending, item)
+			}
+		}
+		f.Close()
+	}
+
+	queueFile, err := os.OpenFile(filepath.Join(dir, "queue.ndjson"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	poppedFile, err := os.OpenFile(filepath.Join(dir, "popped.ndjson"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		queueFile.Close()
+		return nil, err
+	}
+
+	return &FileVisitQueue{
+		pending:    pending,
+		donePopped: donePopped,
+		queueFile:  queueFile,
+		poppedFile: poppedFile,
+		bloomPath:  bloomPath,
+		bloom:      bloom,
+	}, nil
+}
+
+// Push enqueues item unless it was already popped in a prior run, so
+// re-submitting the same WAT file list on --resume doesn't redo finished work.
+func (q *FileVisitQueue) Push(item string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.donePopped[item] {
+		return nil
+	}
+	if _, err := q.queueFile.WriteString(item + "\n"); err != nil {
+		return err
+	}
+	q.pending = append(q.pending, item)
+	return nil
+}
+
+func (q *FileVisitQueue) Pop() (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.pending) == 0 {
+		return "", false
+	}
+	item := q.pending[0]
+	q.pending = q.pending[1:]
+	q.poppedFile.WriteString(item + "\n")
+	q.donePopped[item] = true
+	return item, true
+}
+
+func (q *FileVisitQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// SeenOrMark reports whether key was already (probably) seen by a prior
+// Push/Pop run against this queue dir, marking it seen as a side effect.
+// Backed by a bloom filter, so false positives are possible: a resumed run
+// may drop a handful of genuinely-new rows in exchange for not having to
+// keep every pair in RAM or on disk as a file. The check and the mark
+// happen under the same lock acquisition, so two worker goroutines racing
+// on the same key can't both observe "not seen".
+func (q *FileVisitQueue) SeenOrMark(key string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.bloom.testAndAdd(key)
+}
+
+func (q *FileVisitQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	bits := q.bloom.dump()
+	if err := os.WriteFile(q.bloomPath, bits, 0644); err != nil {
+		return err
+	}
+
+	if err := q.queueFile.Close(); err != nil {
+		return err
+	}
+	return q.poppedFile.Close()
+}
@@ -0,0 +1,110 @@
+// Package harvest finds email addresses and document-file links inside
+// crawled page text, for the `cclinks harvest` command.
+package harvest
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Kind names accepted by --kinds.
+const (
+	KindEmails = "emails"
+	KindDocs   = "docs"
+)
+
+// DefaultDocExtensions is used when --doc-exts is not set.
+var DefaultDocExtensions = []string{".pdf", ".doc", ".docx", ".xls", ".xlsx", ".ppt", ".pptx", ".odt", ".csv"}
+
+// emailPattern is an RFC5322-lite match: enough to catch real-world
+// addresses in crawled HTML/text without pulling in a full grammar.
+var emailPattern = regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`)
+
+// obfuscationReplacer unmasks the handful of obvious anti-scraping tricks
+// seen on crawled pages (`name [at] example [dot] com`, `name (at) example
+// (dot) com`) before the regex runs.
+var obfuscationReplacer = strings.NewReplacer(
+	" [at] ", "@", "[at]", "@", " (at) ", "@", "(at)", "@", " AT ", "@",
+	" [dot] ", ".", "[dot]", ".", " (dot) ", ".", "(dot)", ".", " DOT ", ".",
+)
+
+// EmailMatch is one email address found on a page, with provenance back to
+// the page it was scraped from.
+type EmailMatch struct {
+	Email     string `json:"email"`
+	SourceURL string `json:"source_url"`
+}
+
+// EmailFinder extracts email addresses from WAT/WARC page text, unmasking
+// common obfuscation before matching.
+type EmailFinder struct{}
+
+// Find returns every distinct email address found in text, attributing each
+// to sourceURL.
+func (EmailFinder) Find(text, sourceURL string) []EmailMatch {
+	unmasked := obfuscationReplacer.Replace(text)
+
+	seen := make(map[string]bool)
+	var matches []EmailMatch
+	for _, m := range emailPattern.FindAllString(unmasked, -1) {
+		addr := strings.ToLower(m)
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		matches = append(matches, EmailMatch{Email: addr, SourceURL: sourceURL})
+	}
+	return matches
+}
+
+// DocLink is a link to a document file, identified by extension, found on a
+// page.
+type DocLink struct {
+	URL       string `json:"url"`
+	SourceURL string `json:"source_url"`
+	Extension string `json:"extension"`
+}
+
+// DocFinder extracts links to document files from a page's outgoing link
+// set by matching URL path extensions.
+type DocFinder struct {
+	Extensions []string
+}
+
+// NewDocFinder builds a DocFinder for the given extensions (each should
+// include the leading dot); an empty list falls back to DefaultDocExtensions.
+func NewDocFinder(extensions []string) DocFinder {
+	if len(extensions) == 0 {
+		extensions = DefaultDocExtensions
+	}
+	return DocFinder{Extensions: extensions}
+}
+
+// Find returns the subset of linkURLs that point at a document file by
+// extension, attributing each to sourceURL.
+func (f DocFinder) Find(linkURLs []string, sourceURL string) []DocLink {
+	var docs []DocLink
+	for _, raw := range linkURLs {
+		ext := f.matchExtension(raw)
+		if ext == "" {
+			continue
+		}
+		docs = append(docs, DocLink{URL: raw, SourceURL: sourceURL, Extension: ext})
+	}
+	return docs
+}
+
+func (f DocFinder) matchExtension(rawURL string) string {
+	path := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		path = u.Path
+	}
+	lower := strings.ToLower(path)
+	for _, ext := range f.Extensions {
+		if strings.HasSuffix(lower, strings.ToLower(ext)) {
+			return ext
+		}
+	}
+	return ""
+}
@@ -0,0 +1,255 @@
+// Package ratelimit replaces a flat colly.LimitRule{DomainGlob:"*"} with a
+// per-host (registrable-domain) token bucket that reacts to what a server
+// actually says back: a 429/503 halves the rate and honors Retry-After,
+// clean responses slowly recover it (AIMD), and a host that won't stop
+// erroring gets dropped outright rather than retried forever.
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// recoveryInterval is how long a host must go without a 429/503 before its
+// rate is allowed to climb by one more request/sec, per the "+1 rps per
+// 30s of clean responses" AIMD rule.
+const recoveryInterval = 30 * time.Second
+
+// HostStats is a point-in-time snapshot of one host's limiter state, the
+// shape --stats-addr serves as JSON.
+type HostStats struct {
+	RPS               float64   `json:"rps"`
+	Inflight          int64     `json:"inflight"`
+	ConsecutiveErrors int       `json:"consecutive_errors"`
+	BackoffUntil      time.Time `json:"backoff_until,omitempty"`
+	Dropped           bool      `json:"dropped"`
+}
+
+type hostState struct {
+	mu sync.Mutex
+
+	rps        float64
+	tokens     float64
+	lastRefill time.Time
+
+	lastRPSIncrease   time.Time
+	consecutiveErrors int
+	backoffUntil      time.Time
+	dropped           bool
+
+	inflight int64
+}
+
+// Manager tracks one hostState per registrable domain and gates requests
+// through it.
+type Manager struct {
+	mu      sync.Mutex
+	hosts   map[string]*hostState
+	baseRPS float64
+	maxRPS  float64
+	// ErrorBudget is how many consecutive 429/503/error responses a host
+	// may accumulate before Manager stops issuing it any more tokens. Zero
+	// disables the drop behavior.
+	ErrorBudget int
+}
+
+// NewManager returns a Manager that starts every newly-seen host at
+// baseRPS, lets AIMD recovery climb it back up to maxRPS, and drops a host
+// after errorBudget consecutive 429/503/error responses (0 = never drop).
+func NewManager(baseRPS, maxRPS float64, errorBudget int) *Manager {
+	if baseRPS <= 0 {
+		baseRPS = 2
+	}
+	if maxRPS < baseRPS {
+		maxRPS = baseRPS
+	}
+	return &Manager{
+		hosts:       make(map[string]*hostState),
+		baseRPS:     baseRPS,
+		maxRPS:      maxRPS,
+		ErrorBudget: errorBudget,
+	}
+}
+
+// Key returns the registrable domain (eTLD+1) a host is bucketed under,
+// falling back to the raw host if it can't be parsed against the suffix
+// list, matching scope.SameRegistrableDomain's fallback.
+func Key(host string) string {
+	reg, err := publicsuffix.EffectiveTLDPlusOne(stripPort(host))
+	if err != nil {
+		return stripPort(host)
+	}
+	return reg
+}
+
+func stripPort(host string) string {
+	for i := len(host) - 1; i >= 0; i-- {
+		if host[i] == ':' {
+			return host[:i]
+		}
+		if host[i] == ']' || host[i] == '.' {
+			break
+		}
+	}
+	return host
+}
+
+func (m *Manager) state(host string) *hostState {
+	key := Key(host)
+
+	m.mu.Lock()
+	hs, ok := m.hosts[key]
+	if !ok {
+		hs = &hostState{rps: m.baseRPS, tokens: m.baseRPS, lastRefill: time.Now(), lastRPSIncrease: time.Now()}
+		m.hosts[key] = hs
+	}
+	m.mu.Unlock()
+	return hs
+}
+
+// Acquire blocks until host has a free token (or its backoff window has
+// passed), then marks one request in flight. It returns an error without
+// blocking if the host has been dropped.
+func (m *Manager) Acquire(host string) error {
+	hs := m.state(host)
+
+	for {
+		hs.mu.Lock()
+		if hs.dropped {
+			hs.mu.Unlock()
+			return fmt.Errorf("ratelimit: host %s dropped after %d consecutive errors", Key(host), m.ErrorBudget)
+		}
+
+		now := time.Now()
+		if now.Before(hs.backoffUntil) {
+			wait := hs.backoffUntil.Sub(now)
+			hs.mu.Unlock()
+			time.Sleep(wait)
+			continue
+		}
+
+		hs.refillLocked(now)
+		if hs.tokens >= 1 {
+			hs.tokens--
+			atomic.AddInt64(&hs.inflight, 1)
+			hs.mu.Unlock()
+			return nil
+		}
+
+		waitSecs := (1 - hs.tokens) / hs.rps
+		hs.mu.Unlock()
+		time.Sleep(time.Duration(waitSecs * float64(time.Second)))
+	}
+}
+
+func (hs *hostState) refillLocked(now time.Time) {
+	elapsed := now.Sub(hs.lastRefill).Seconds()
+	hs.lastRefill = now
+	hs.tokens += elapsed * hs.rps
+	if hs.tokens > hs.rps {
+		hs.tokens = hs.rps // bucket holds at most one second's worth of burst
+	}
+}
+
+// Release marks a request no longer in flight; call it (typically via
+// defer) once a request Acquire let through has finished, success or not.
+func (m *Manager) Release(host string) {
+	atomic.AddInt64(&m.state(host).inflight, -1)
+}
+
+// Report tells Manager how a request to host turned out, so it can halve
+// the rate and set a backoff on 429/503 (honoring retryAfter, parsed as
+// either a delta-seconds value or an HTTP-date), recover it by AIMD on a
+// clean response, and drop the host outright once ErrorBudget consecutive
+// failures accumulate.
+func (m *Manager) Report(host string, statusCode int, retryAfter string) {
+	hs := m.state(host)
+
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable || statusCode == 0 {
+		hs.consecutiveErrors++
+		hs.rps /= 2
+		if hs.rps < 0.1 {
+			hs.rps = 0.1
+		}
+		hs.tokens = 0
+		// Restart the recovery clock: the next +1 rps needs a full
+		// recoveryInterval without another 429/503.
+		hs.lastRPSIncrease = time.Now()
+
+		if wait, ok := parseRetryAfter(retryAfter); ok {
+			hs.backoffUntil = time.Now().Add(wait)
+		} else {
+			hs.backoffUntil = time.Now().Add(time.Duration(1<<uint(minInt(hs.consecutiveErrors, 6))) * time.Second)
+		}
+
+		if m.ErrorBudget > 0 && hs.consecutiveErrors >= m.ErrorBudget {
+			hs.dropped = true
+		}
+		return
+	}
+
+	hs.consecutiveErrors = 0
+	if now := time.Now(); now.Sub(hs.lastRPSIncrease) >= recoveryInterval {
+		hs.rps++
+		if hs.rps > m.maxRPS {
+			hs.rps = m.maxRPS
+		}
+		hs.lastRPSIncrease = now
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// parseRetryAfter parses a Retry-After header value, which per RFC 9110
+// is either a delta-seconds integer or an HTTP-date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
+
+// Stats returns a snapshot of every host Manager has seen so far, keyed by
+// registrable domain.
+func (m *Manager) Stats() map[string]HostStats {
+	m.mu.Lock()
+	hosts := make(map[string]*hostState, len(m.hosts))
+	for k, hs := range m.hosts {
+		hosts[k] = hs
+	}
+	m.mu.Unlock()
+
+	out := make(map[string]HostStats, len(hosts))
+	for key, hs := range hosts {
+		hs.mu.Lock()
+		out[key] = HostStats{
+			RPS:               hs.rps,
+			Inflight:          atomic.LoadInt64(&hs.inflight),
+			ConsecutiveErrors: hs.consecutiveErrors,
+			BackoffUntil:      hs.backoffUntil,
+			Dropped:           hs.dropped,
+		}
+		hs.mu.Unlock()
+	}
+	return out
+}
@@ -0,0 +1,101 @@
+// Package render provides a pluggable JS-rendering fallback for crawlers
+// whose static HTTP fetch comes back as an empty SPA shell. Renderer is
+// deliberately small (one method, one result type) so a crawler can default
+// to NoopRenderer when no headless browser is configured and swap in
+// ChromeDPRenderer only when --render=auto is actually requested.
+package render
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Result is what a Renderer hands back after a page has finished loading.
+type Result struct {
+	HTML       string
+	RenderedBy string
+}
+
+// Renderer turns a URL into post-render HTML, waiting for the page to
+// settle before dumping its DOM.
+type Renderer interface {
+	Render(ctx context.Context, targetURL string, timeout time.Duration) (Result, error)
+}
+
+// Noop is the default Renderer: it never renders anything, so callers that
+// don't configure a real one (no --render=auto) pay no cost and get a
+// plain error back rather than a nil-pointer surprise.
+type Noop struct{}
+
+func (Noop) Render(ctx context.Context, targetURL string, timeout time.Duration) (Result, error) {
+	return Result{}, fmt.Errorf("render: no renderer configured")
+}
+
+// ChromeDPRenderer renders pages with a bounded pool of chromedp (headless
+// Chrome) contexts, all sharing one allocator so the pool never spawns more
+// than --render-workers browser tabs at once.
+type ChromeDPRenderer struct {
+	allocCtx context.Context
+	cancel   context.CancelFunc
+	sem      chan struct{}
+
+	// WaitSelector, when set, is a CSS selector ChromeDPRenderer waits to
+	// become visible before dumping the DOM; otherwise it waits for the
+	// body element to be ready and gives the page a brief settle period,
+	// which approximates "networkidle" well enough for SPA shells without
+	// pulling in chromedp's lower-level network-event plumbing.
+	WaitSelector string
+}
+
+// NewChromeDPRenderer starts a shared headless-Chrome allocator and bounds
+// concurrent render calls to workers tabs at a time.
+func NewChromeDPRenderer(workers int, waitSelector string) *ChromeDPRenderer {
+	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), chromedp.DefaultExecAllocatorOptions[:]...)
+	if workers <= 0 {
+		workers = 1
+	}
+	return &ChromeDPRenderer{
+		allocCtx:     allocCtx,
+		cancel:       cancel,
+		sem:          make(chan struct{}, workers),
+		WaitSelector: waitSelector,
+	}
+}
+
+// Close shuts down the shared allocator, killing any browser process it
+// started.
+func (r *ChromeDPRenderer) Close() {
+	r.cancel()
+}
+
+// Render navigates to targetURL in a fresh tab drawn from the pool, waits
+// for it to settle, and returns the rendered document's outer HTML.
+func (r *ChromeDPRenderer) Render(ctx context.Context, targetURL string, timeout time.Duration) (Result, error) {
+	r.sem <- struct{}{}
+	defer func() { <-r.sem }()
+
+	tabCtx, tabCancel := chromedp.NewContext(r.allocCtx)
+	defer tabCancel()
+
+	tabCtx, timeoutCancel := context.WithTimeout(tabCtx, timeout)
+	defer timeoutCancel()
+
+	actions := []chromedp.Action{chromedp.Navigate(targetURL)}
+	if r.WaitSelector != "" {
+		actions = append(actions, chromedp.WaitVisible(r.WaitSelector, chromedp.ByQuery))
+	} else {
+		actions = append(actions, chromedp.WaitReady("body", chromedp.ByQuery), chromedp.Sleep(500*time.Millisecond))
+	}
+
+	var html string
+	actions = append(actions, chromedp.OuterHTML("html", &html, chromedp.ByQuery))
+
+	if err := chromedp.Run(tabCtx, actions...); err != nil {
+		return Result{}, fmt.Errorf("chromedp: %w", err)
+	}
+
+	return Result{HTML: html, RenderedBy: "chromedp"}, nil
+}
@@ -0,0 +1,290 @@
+// Package robots fetches and caches robots.txt per host, so a crawler can
+// check whether a path is allowed (and how long to wait between requests)
+// before it visits it, and discovers the Sitemap: directives a site
+// advertises. See sitemap.go for parsing the sitemaps themselves.
+package robots
+
+import (
+	"bufio"
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rule is one Allow/Disallow line within a group.
+type rule struct {
+	path  string
+	allow bool
+}
+
+// group is the rule set for one or more User-agent: lines that shared a
+// block in robots.txt.
+type group struct {
+	rules      []rule
+	crawlDelay time.Duration
+}
+
+// allows reports whether path is allowed under g, using the standard
+// longest-matching-prefix-wins rule (ties go to Allow). A nil group (no
+// matching User-agent block at all) allows everything, matching the
+// robots.txt convention that an absent group imposes no restriction.
+func (g *group) allows(path string) bool {
+	if g == nil {
+		return true
+	}
+	bestLen := -1
+	bestAllow := true
+	for _, r := range g.rules {
+		if r.path == "" {
+			if !r.allow {
+				continue // an empty Disallow means "allow everything"
+			}
+		}
+		if !strings.HasPrefix(path, r.path) {
+			continue
+		}
+		if len(r.path) > bestLen || (len(r.path) == bestLen && r.allow) {
+			bestLen = len(r.path)
+			bestAllow = r.allow
+		}
+	}
+	return bestAllow
+}
+
+// file is one host's parsed robots.txt.
+type file struct {
+	groups   map[string]*group // lowercased User-agent token -> group; "*" is the wildcard
+	sitemaps []string
+}
+
+// groupFor returns the most specific group matching userAgent, falling
+// back to the wildcard group, or nil if robots.txt has neither (i.e.
+// nothing is restricted).
+func (f *file) groupFor(userAgent string) *group {
+	ua := strings.ToLower(userAgent)
+	var best *group
+	bestLen := 0
+	for token, g := range f.groups {
+		if token == "*" {
+			continue
+		}
+		if (strings.Contains(ua, token) || strings.Contains(token, ua)) && len(token) > bestLen {
+			best, bestLen = g, len(token)
+		}
+	}
+	if best != nil {
+		return best
+	}
+	return f.groups["*"]
+}
+
+// parse reads a robots.txt body into a file. Consecutive User-agent lines
+// share one group until a Disallow/Allow/Crawl-delay line is seen, after
+// which the next User-agent line starts a new group, per the standard
+// robots.txt grammar. Unrecognized directives are ignored.
+func parse(body string) *file {
+	f := &file{groups: map[string]*group{}}
+	var current []*group
+	startingGroup := true
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		idx := strings.Index(line, ":")
+		if idx < 0 {
+			continue
+		}
+		field := strings.ToLower(strings.TrimSpace(line[:idx]))
+		value := strings.TrimSpace(line[idx+1:])
+		if c := strings.IndexByte(value, '#'); c >= 0 {
+			value = strings.TrimSpace(value[:c])
+		}
+
+		switch field {
+		case "user-agent":
+			if !startingGroup {
+				current = nil
+				startingGroup = true
+			}
+			token := strings.ToLower(value)
+			g, ok := f.groups[token]
+			if !ok {
+				g = &group{}
+				f.groups[token] = g
+			}
+			current = append(current, g)
+		case "disallow":
+			startingGroup = false
+			for _, g := range current {
+				g.rules = append(g.rules, rule{path: value, allow: false})
+			}
+		case "allow":
+			startingGroup = false
+			for _, g := range current {
+				g.rules = append(g.rules, rule{path: value, allow: true})
+			}
+		case "crawl-delay":
+			startingGroup = false
+			if secs, err := strconv.ParseFloat(value, 64); err == nil {
+				for _, g := range current {
+					g.crawlDelay = time.Duration(secs * float64(time.Second))
+				}
+			}
+		case "sitemap":
+			f.sitemaps = append(f.sitemaps, value)
+		}
+	}
+	return f
+}
+
+type cacheItem struct {
+	host      string
+	file      *file
+	fetchedAt time.Time
+}
+
+// Cache fetches and caches one robots.txt per host, LRU-evicted past
+// maxEntries and re-fetched once an entry is older than ttl.
+type Cache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	client     *http.Client
+
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+// NewCache returns a Cache that re-fetches a host's robots.txt after ttl
+// and keeps at most maxEntries hosts cached at once (0 defaults to 1000).
+func NewCache(ttl time.Duration, maxEntries int, client *http.Client) *Cache {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+	return &Cache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		client:     client,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+func (c *Cache) get(ctx context.Context, scheme, host string) *file {
+	c.mu.Lock()
+	if el, ok := c.items[host]; ok {
+		item := el.Value.(*cacheItem)
+		if time.Since(item.fetchedAt) < c.ttl {
+			c.ll.MoveToFront(el)
+			c.mu.Unlock()
+			return item.file
+		}
+	}
+	c.mu.Unlock()
+
+	f := fetch(ctx, c.client, scheme, host)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[host]; ok {
+		item := el.Value.(*cacheItem)
+		item.file = f
+		item.fetchedAt = time.Now()
+		c.ll.MoveToFront(el)
+		return f
+	}
+
+	el := c.ll.PushFront(&cacheItem{host: host, file: f, fetchedAt: time.Now()})
+	c.items[host] = el
+	if c.ll.Len() > c.maxEntries {
+		oldest := c.ll.Back()
+		if oldest != nil {
+			c.ll.Remove(oldest)
+			delete(c.items, oldest.Value.(*cacheItem).host)
+		}
+	}
+	return f
+}
+
+// fetch retrieves host's robots.txt; any failure (network error, non-200,
+// missing file) is treated as an empty file — i.e. nothing disallowed —
+// which is the standard robots.txt fallback when the file can't be read.
+func fetch(ctx context.Context, client *http.Client, scheme, host string) *file {
+	reqURL := fmt.Sprintf("%s://%s/robots.txt", scheme, host)
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return &file{groups: map[string]*group{}}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return &file{groups: map[string]*group{}}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return &file{groups: map[string]*group{}}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20)) // robots.txt is never legitimately huge
+	if err != nil {
+		return &file{groups: map[string]*group{}}
+	}
+	return parse(string(body))
+}
+
+// Allowed reports whether userAgent may fetch rawURL according to its
+// host's robots.txt. A malformed rawURL is allowed rather than erroring,
+// since the caller is about to fetch it anyway and will get its own error
+// if it's truly unreachable.
+func (c *Cache) Allowed(ctx context.Context, userAgent, rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return true
+	}
+
+	f := c.get(ctx, u.Scheme, u.Host)
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return f.groupFor(userAgent).allows(path)
+}
+
+// CrawlDelay returns the Crawl-delay robots.txt asked userAgent to honor
+// for rawURL's host, if any.
+func (c *Cache) CrawlDelay(ctx context.Context, userAgent, rawURL string) (time.Duration, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return 0, false
+	}
+	g := c.get(ctx, u.Scheme, u.Host).groupFor(userAgent)
+	if g == nil || g.crawlDelay == 0 {
+		return 0, false
+	}
+	return g.crawlDelay, true
+}
+
+// Sitemaps returns the Sitemap: directives rawURL's host's robots.txt
+// advertises.
+func (c *Cache) Sitemaps(ctx context.Context, rawURL string) ([]string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return c.get(ctx, u.Scheme, u.Host).sitemaps, nil
+}
@@ -0,0 +1,127 @@
+package robots
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Entry is one <url> discovered in a sitemap.
+type Entry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+type urlSetDoc struct {
+	URLs []Entry `xml:"url"`
+}
+
+type sitemapIndexDoc struct {
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// maxSitemapDepth bounds how deep FetchSitemap recurses into nested
+// sitemap indexes, so a misconfigured or malicious index pointing at
+// itself can't recurse forever.
+const maxSitemapDepth = 5
+
+// DiscoverSitemapURLs returns the sitemap URLs to check for host: whatever
+// Sitemap: directives robots.txt advertised, plus the two conventional
+// locations (/sitemap.xml, /sitemap_index.xml) when robots.txt named
+// neither.
+func DiscoverSitemapURLs(robotsSitemaps []string, scheme, host string) []string {
+	if len(robotsSitemaps) > 0 {
+		return robotsSitemaps
+	}
+	return []string{
+		fmt.Sprintf("%s://%s/sitemap.xml", scheme, host),
+		fmt.Sprintf("%s://%s/sitemap_index.xml", scheme, host),
+	}
+}
+
+// FetchSitemap retrieves sitemapURL and returns every <url> entry it (or,
+// for a sitemap index, its children) contains. Gzip-compressed sitemaps
+// (Content-Encoding: gzip, or a .gz URL) are decompressed transparently.
+func FetchSitemap(ctx context.Context, client *http.Client, sitemapURL string) ([]Entry, error) {
+	return fetchSitemap(ctx, client, sitemapURL, 0)
+}
+
+func fetchSitemap(ctx context.Context, client *http.Client, sitemapURL string, depth int) ([]Entry, error) {
+	if depth > maxSitemapDepth {
+		return nil, fmt.Errorf("sitemap: recursion too deep at %s", sitemapURL)
+	}
+
+	body, err := fetchSitemapBody(ctx, client, sitemapURL)
+	if err != nil {
+		return nil, fmt.Errorf("sitemap: %w", err)
+	}
+
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(body, &root); err != nil {
+		return nil, fmt.Errorf("sitemap: %s: decode failed: %w", sitemapURL, err)
+	}
+
+	switch root.XMLName.Local {
+	case "sitemapindex":
+		var idx sitemapIndexDoc
+		if err := xml.Unmarshal(body, &idx); err != nil {
+			return nil, fmt.Errorf("sitemap: %s: decode index failed: %w", sitemapURL, err)
+		}
+		var all []Entry
+		for _, s := range idx.Sitemaps {
+			if s.Loc == "" {
+				continue
+			}
+			children, err := fetchSitemap(ctx, client, s.Loc, depth+1)
+			if err != nil {
+				continue // a broken child sitemap shouldn't discard the rest of the index
+			}
+			all = append(all, children...)
+		}
+		return all, nil
+	case "urlset":
+		var set urlSetDoc
+		if err := xml.Unmarshal(body, &set); err != nil {
+			return nil, fmt.Errorf("sitemap: %s: decode urlset failed: %w", sitemapURL, err)
+		}
+		return set.URLs, nil
+	default:
+		return nil, fmt.Errorf("sitemap: %s: unrecognized root element %q", sitemapURL, root.XMLName.Local)
+	}
+}
+
+func fetchSitemapBody(ctx context.Context, client *http.Client, sitemapURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", sitemapURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("http %d", resp.StatusCode)
+	}
+
+	reader := resp.Body
+	var gzReader io.ReadCloser
+	if resp.Header.Get("Content-Encoding") == "gzip" || strings.HasSuffix(sitemapURL, ".gz") {
+		gzReader, err = gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		defer gzReader.Close()
+		reader = gzReader
+	}
+
+	return io.ReadAll(io.LimitReader(reader, 64<<20)) // sitemaps can legitimately run to tens of MB
+}
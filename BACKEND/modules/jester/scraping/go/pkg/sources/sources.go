@@ -0,0 +1,253 @@
+// Package sources provides third-party URL providers — Wayback Machine,
+// CommonCrawl, crt.sh — that turn a bare domain into a list of historical
+// URLs a crawl can seed itself with, the same role rod_crawler's own
+// discover-command SeedSource interface plays, but shaped to stream
+// straight into colly_crawler's frontier rather than writing a one-shot
+// seeds.json.
+package sources
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Source is a pluggable provider of historical URLs for a domain.
+type Source interface {
+	// Name identifies the provider; preserved as CrawlResult.Source so a
+	// consumer can tell which URLs came from the live crawl versus which
+	// provider surfaced them ahead of time.
+	Name() string
+	Fetch(ctx context.Context, domain string, includeSubs bool) ([]string, error)
+}
+
+// WaybackSource queries the Internet Archive's CDX API.
+type WaybackSource struct {
+	Client *http.Client
+}
+
+func (s *WaybackSource) Name() string { return "wayback" }
+
+func (s *WaybackSource) Fetch(ctx context.Context, domain string, includeSubs bool) ([]string, error) {
+	target := domain
+	if includeSubs {
+		target = "*." + domain
+	}
+
+	reqURL := fmt.Sprintf("http://web.archive.org/cdx/search/cdx?url=%s&output=json&fl=original&collapse=urlkey", target)
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("wayback: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("wayback: http %d", resp.StatusCode)
+	}
+
+	// The CDX JSON API is a JSON array-of-arrays with a header row first:
+	// [["original"], [...], ...]
+	var rows [][]string
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("wayback: decode failed: %w", err)
+	}
+
+	var urls []string
+	for i, row := range rows {
+		if i == 0 || len(row) < 1 {
+			continue
+		}
+		urls = append(urls, row[0])
+	}
+	return urls, nil
+}
+
+func (s *WaybackSource) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+// CommonCrawlSource queries whichever CC archive collinfo.json reports as
+// the latest (the first entry), the same index real CommonCrawl users are
+// pointed at when they don't pin a specific crawl.
+type CommonCrawlSource struct {
+	Client *http.Client
+}
+
+func (s *CommonCrawlSource) Name() string { return "commoncrawl" }
+
+func (s *CommonCrawlSource) Fetch(ctx context.Context, domain string, includeSubs bool) ([]string, error) {
+	cdxAPI, err := s.latestCDXAPI(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("commoncrawl: %w", err)
+	}
+
+	target := domain
+	if includeSubs {
+		target = "*." + domain
+	}
+
+	reqURL := fmt.Sprintf("%s?url=%s&output=json", cdxAPI, target)
+	lines, err := getLines(ctx, s.client(), reqURL)
+	if err != nil {
+		return nil, fmt.Errorf("commoncrawl: %w", err)
+	}
+
+	var urls []string
+	for _, line := range lines {
+		var rec struct {
+			URL string `json:"url"`
+		}
+		if json.Unmarshal([]byte(line), &rec) == nil && rec.URL != "" {
+			urls = append(urls, rec.URL)
+		}
+	}
+	return urls, nil
+}
+
+func (s *CommonCrawlSource) latestCDXAPI(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://index.commoncrawl.org/collinfo.json", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("collinfo.json: http %d", resp.StatusCode)
+	}
+
+	var collections []struct {
+		CDXAPI string `json:"cdx-api"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&collections); err != nil {
+		return "", fmt.Errorf("collinfo.json: decode failed: %w", err)
+	}
+	if len(collections) == 0 || collections[0].CDXAPI == "" {
+		return "", fmt.Errorf("collinfo.json: no collections returned")
+	}
+	return collections[0].CDXAPI, nil
+}
+
+func (s *CommonCrawlSource) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+// CrtShSource queries crt.sh's certificate-transparency log search for
+// subdomains seen in issued certificates. Unlike Wayback/CommonCrawl it
+// surfaces hostnames, not URLs, so Fetch turns each into a bare https://
+// seed; includeSubs=false keeps only the apex domain itself, since crt.sh
+// has no server-side exact/subdomain distinction to query for.
+type CrtShSource struct {
+	Client *http.Client
+}
+
+func (s *CrtShSource) Name() string { return "crt" }
+
+func (s *CrtShSource) Fetch(ctx context.Context, domain string, includeSubs bool) ([]string, error) {
+	reqURL := fmt.Sprintf("https://crt.sh/?q=%%25.%s&output=json", domain)
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("crt.sh: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("crt.sh: http %d", resp.StatusCode)
+	}
+
+	var entries []struct {
+		NameValue string `json:"name_value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("crt.sh: decode failed: %w", err)
+	}
+
+	seen := map[string]bool{}
+	var urls []string
+	for _, entry := range entries {
+		for _, host := range strings.Split(entry.NameValue, "\n") {
+			host = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(host, "*.")))
+			if host == "" || seen[host] {
+				continue
+			}
+			if !includeSubs && host != strings.ToLower(domain) {
+				continue
+			}
+			seen[host] = true
+			urls = append(urls, "https://"+host+"/")
+		}
+	}
+	return urls, nil
+}
+
+func (s *CrtShSource) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+// getLines issues a GET and splits the body into lines, the shape CC's CDX
+// API returns (one JSON object per line, not a JSON array).
+func getLines(ctx context.Context, client *http.Client, reqURL string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("http %d", resp.StatusCode)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	buf := make([]byte, 1024*1024)
+	scanner.Buffer(buf, 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// Resolve turns a --other-source=wayback,commoncrawl,crt flag value into
+// Source implementations, skipping (with a warning written to warnf, if
+// set) any name it doesn't recognize.
+func Resolve(names []string, client *http.Client, warnf func(format string, args ...interface{})) []Source {
+	var resolved []Source
+	for _, name := range names {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "wayback":
+			resolved = append(resolved, &WaybackSource{Client: client})
+		case "commoncrawl", "cc":
+			resolved = append(resolved, &CommonCrawlSource{Client: client})
+		case "crt", "crtsh", "crt.sh":
+			resolved = append(resolved, &CrtShSource{Client: client})
+		default:
+			if warnf != nil {
+				warnf("unknown --other-source %q, skipping", name)
+			}
+		}
+	}
+	return resolved
+}
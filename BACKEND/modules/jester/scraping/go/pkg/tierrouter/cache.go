@@ -0,0 +1,61 @@
+package tierrouter
+
+import (
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+var tierBucket = []byte("tier_decisions")
+
+// TierCache is a bbolt-backed record of which Tier last actually served a
+// domain, so Dispatcher.Crawl can skip straight to it on later URLs
+// instead of re-paying the cost of the cheaper tiers that already proved
+// insufficient for that host.
+type TierCache struct {
+	db *bbolt.DB
+}
+
+// OpenTierCache opens (creating if needed) the bbolt db at path.
+func OpenTierCache(path string) (*TierCache, error) {
+	db, err := bbolt.Open(path, 0644, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("could not open tier cache %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(tierBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &TierCache{db: db}, nil
+}
+
+func (c *TierCache) Close() error {
+	return c.db.Close()
+}
+
+// Get returns the tier last recorded for domain, if any.
+func (c *TierCache) Get(domain string) (Tier, bool) {
+	var tier Tier
+	var ok bool
+	c.db.View(func(tx *bbolt.Tx) error {
+		if v := tx.Bucket(tierBucket).Get([]byte(domain)); v != nil {
+			tier, ok = Tier(v), true
+		}
+		return nil
+	})
+	return tier, ok
+}
+
+// Record persists the tier that actually served domain successfully.
+func (c *TierCache) Record(domain string, tier Tier) error {
+	return c.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(tierBucket).Put([]byte(domain), []byte(tier))
+	})
+}
@@ -0,0 +1,103 @@
+// Package tierrouter implements the Colly -> Rod -> Playwright escalation
+// path the other crawlers' comments describe but never actually drove:
+// Classify inspects a page Colly already fetched and decides whether it
+// needs a heavier tier, and TierCache remembers that decision per domain
+// so later URLs on the same host skip the cheap tier entirely.
+package tierrouter
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Tier identifies one of the three crawler backends, cheapest first.
+type Tier string
+
+const (
+	TierColly      Tier = "colly"
+	TierRod        Tier = "rod"
+	TierPlaywright Tier = "playwright"
+)
+
+const (
+	// minHTMLBytes below which a page is assumed to be a JS-rendered shell
+	// rather than real content.
+	minHTMLBytes = 2000
+	// minAnchors below which a page is assumed not to have rendered its
+	// navigation yet.
+	minAnchors = 3
+)
+
+// spaMarkers are substrings that show up in the initial HTML of
+// JS-framework apps before their client-side router has run, matching
+// colly_crawler's spaIndicators list.
+var spaMarkers = []string{
+	`<div id="root"></div>`,
+	`<div id="root">`,
+	`<div id="app"></div>`,
+	`<div id="app">`,
+	`<div id="__next"></div>`,
+	`<div id="__next">`,
+	`<app-root></app-root>`,
+	`window.__NUXT__`,
+	`window.__NEXT_DATA__`,
+	`window.__INITIAL_STATE__`,
+	`window.__PRELOADED_STATE__`,
+}
+
+// cloudflareFingerprints identify an anti-bot challenge page, as distinct
+// from "this page just needs JS" — no amount of Rod rendering gets past
+// one of these, so Classify/Dispatcher treat it as a separate signal from
+// the SPA-marker checks above.
+var cloudflareFingerprints = []string{
+	"Checking your browser before accessing",
+	"cf-browser-verification",
+	"Attention Required! | Cloudflare",
+	"__cf_chl_",
+	"Just a moment...",
+}
+
+var (
+	anchorRegex   = regexp.MustCompile(`(?i)<a\s+[^>]*href=`)
+	noscriptRegex = regexp.MustCompile(`(?is)<noscript[^>]*>(.*?)</noscript>`)
+)
+
+// Classify decides which tier a URL should be (re)crawled at, given the
+// HTML Colly already fetched for it. It only ever returns TierColly or
+// TierRod: the further escalation to TierPlaywright depends on how Rod
+// itself fares (timeouts, anti-bot walls), which only Dispatcher.Crawl can
+// observe since it requires actually having tried Rod.
+func Classify(htmlFromColly string) Tier {
+	if IsAntiBotWall(htmlFromColly) {
+		return TierRod
+	}
+	if len(htmlFromColly) < minHTMLBytes {
+		return TierRod
+	}
+	for _, marker := range spaMarkers {
+		if strings.Contains(htmlFromColly, marker) {
+			return TierRod
+		}
+	}
+	if m := noscriptRegex.FindStringSubmatch(htmlFromColly); len(m) > 1 {
+		content := strings.ToLower(m[1])
+		if strings.Contains(content, "javascript") && strings.Contains(content, "enable") {
+			return TierRod
+		}
+	}
+	if len(anchorRegex.FindAllString(htmlFromColly, -1)) < minAnchors {
+		return TierRod
+	}
+	return TierColly
+}
+
+// IsAntiBotWall reports whether html looks like a Cloudflare (or similar)
+// challenge page rather than the site's real content.
+func IsAntiBotWall(html string) bool {
+	for _, fp := range cloudflareFingerprints {
+		if strings.Contains(html, fp) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+// Package trigram prefilters many candidate strings against a byte stream
+// using three-byte windows, the same technique code-search engines use to
+// narrow candidates before running an exact match. It lets a caller scanning
+// large lines against many targets (e.g. cclinks sniper's --target-domain
+// list) skip the exact substring check, and any downstream JSON parse, for
+// targets that provably can't be present in a given line.
+package trigram
+
+import "strings"
+
+// Index holds, for each of a fixed list of targets, which of its trigrams
+// have been seen in the line currently being scanned.
+type Index struct {
+	targets      []string
+	trigramOwner map[[3]byte][]int // trigram -> indices into targets that need it
+	needed       []int             // distinct trigram count required per target
+	// short marks, per target index, a target shorter than 3 bytes: it has
+	// no trigram of its own, so it's matched by a direct substring check in
+	// Candidates instead of trigram coverage (padding it into a fake
+	// trigram would produce a byte sequence that can never appear in real
+	// scanned text, a permanent false negative for that target).
+	short []bool
+}
+
+// New builds an Index over targets, lower-cased.
+func New(targets []string) *Index {
+	idx := &Index{
+		targets:      make([]string, len(targets)),
+		trigramOwner: make(map[[3]byte][]int),
+		needed:       make([]int, len(targets)),
+		short:        make([]bool, len(targets)),
+	}
+	for i, t := range targets {
+		lower := strings.ToLower(t)
+		idx.targets[i] = lower
+		if len(lower) < 3 {
+			idx.short[i] = true
+			continue
+		}
+		set := trigramsOf(lower)
+		idx.needed[i] = len(set)
+		for tri := range set {
+			idx.trigramOwner[tri] = append(idx.trigramOwner[tri], i)
+		}
+	}
+	return idx
+}
+
+// trigramsOf returns the set of distinct 3-byte windows in s. Callers must
+// only pass s of length >= 3; shorter targets are handled separately (see
+// Index.short).
+func trigramsOf(s string) map[[3]byte]struct{} {
+	b := []byte(s)
+	set := make(map[[3]byte]struct{}, len(b)-2)
+	for i := 0; i+3 <= len(b); i++ {
+		set[[3]byte{b[i], b[i+1], b[i+2]}] = struct{}{}
+	}
+	return set
+}
+
+// Candidates streams line's bytes once, lower-cased, and returns the subset
+// of targets whose full trigram set was covered. Coverage is necessary but
+// not sufficient for line actually containing the target (trigram order
+// isn't checked), so callers must still run an exact Contains check on the
+// returned candidates before trusting a match.
+func (idx *Index) Candidates(line string) []string {
+	b := []byte(strings.ToLower(line))
+	found := make([]int, len(idx.targets))
+	seen := make(map[[3]byte]struct{})
+
+	for i := 0; i+3 <= len(b); i++ {
+		tri := [3]byte{b[i], b[i+1], b[i+2]}
+		if _, dup := seen[tri]; dup {
+			continue
+		}
+		owners, ok := idx.trigramOwner[tri]
+		if !ok {
+			continue
+		}
+		seen[tri] = struct{}{}
+		for _, ti := range owners {
+			found[ti]++
+		}
+	}
+
+	lowerLine := string(b)
+
+	var out []string
+	for i, t := range idx.targets {
+		if idx.short[i] {
+			if strings.Contains(lowerLine, t) {
+				out = append(out, t)
+			}
+			continue
+		}
+		if found[i] >= idx.needed[i] {
+			out = append(out, t)
+		}
+	}
+	return out
+}
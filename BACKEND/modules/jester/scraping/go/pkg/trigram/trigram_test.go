@@ -0,0 +1,65 @@
+package trigram
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCandidates(t *testing.T) {
+	cases := []struct {
+		name    string
+		targets []string
+		line    string
+		want    []string
+	}{
+		{
+			name:    "short target shorter than three bytes matches via substring",
+			targets: []string{"ab", "a", "example.com"},
+			line:    "see https://ab.example.org for details",
+			want:    []string{"ab", "a"},
+		},
+		{
+			name:    "single byte target matches",
+			targets: []string{"x"},
+			line:    "xyz",
+			want:    []string{"x"},
+		},
+		{
+			name:    "short target absent from line is not a candidate",
+			targets: []string{"zz"},
+			line:    "hello world",
+			want:    nil,
+		},
+		{
+			name:    "ordinary three-or-more byte target requires full trigram coverage",
+			targets: []string{"example.com"},
+			line:    "visit example.com today",
+			want:    []string{"example.com"},
+		},
+		{
+			name:    "three-or-more byte target not present is not a candidate",
+			targets: []string{"example.com"},
+			line:    "visit example.org today",
+			want:    nil,
+		},
+		{
+			name:    "case-insensitive match",
+			targets: []string{"Example.COM"},
+			line:    "VISIT EXAMPLE.COM TODAY",
+			want:    []string{"example.com"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			idx := New(c.targets)
+			got := idx.Candidates(c.line)
+			sort.Strings(got)
+			sort.Strings(c.want)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("Candidates(%q) with targets %v = %v, want %v", c.line, c.targets, got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+// Package warcout assembles a WARC/1.1 output file for cclinks'
+// --warc-output: one `response` record per source page (copied
+// byte-for-byte from the original CC WARC record so its payload digest
+// stays intact) plus one `metadata` record per outlink extracted from that
+// page, each referring back to the page's response record. This is what pywb/warcio/replayweb.page
+// replay against, alongside the JSON/CSV/NDJSON link records cclinks
+// writes separately.
+package warcout
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// WarcWriter writes WARC records to a single output file.
+type WarcWriter struct {
+	file *os.File
+}
+
+func NewWarcWriter(path string) (*WarcWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not create WARC output %s: %w", path, err)
+	}
+	return &WarcWriter{file: f}, nil
+}
+
+func (w *WarcWriter) Close() error {
+	return w.file.Close()
+}
+
+// NewWarcRecordID mints a urn:uuid WARC-Record-ID for records a caller
+// authors itself (metadata records); a response record copied from an
+// existing WARC file keeps the ID already in its header instead.
+func NewWarcRecordID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// WriteRawRecord appends an already gzip-compressed WARC record (as fetched
+// verbatim from data.commoncrawl.org) to the output. WARC files are just a
+// concatenation of per-record gzip members, so this needs no decompression.
+func (w *WarcWriter) WriteRawRecord(raw []byte) error {
+	_, err := w.file.Write(raw)
+	return err
+}
+
+// WriteMetadata writes a `metadata` record referring back to refersToID
+// (the WARC-Record-ID of the response record it describes), carrying body
+// as its content (here, one JSON-encoded OutlinkResult from that page).
+func (w *WarcWriter) WriteMetadata(targetURI, refersToID string, body []byte) error {
+	id := NewWarcRecordID()
+	date := time.Now().UTC().Format("2006-01-02T15:04:05Z")
+
+	var header strings.Builder
+	header.WriteString("WARC/1.1\r\n")
+	header.WriteString("WARC-Type: metadata\r\n")
+	header.WriteString(fmt.Sprintf("WARC-Record-ID: %s\r\n", id))
+	header.WriteString(fmt.Sprintf("WARC-Date: %s\r\n", date))
+	header.WriteString(fmt.Sprintf("WARC-Target-URI: %s\r\n", targetURI))
+	header.WriteString(fmt.Sprintf("WARC-Refers-To: %s\r\n", refersToID))
+	header.WriteString("Content-Type: application/warc-fields\r\n")
+	header.WriteString(fmt.Sprintf("Content-Length: %d\r\n", len(body)))
+	header.WriteString("\r\n")
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(header.String())); err != nil {
+		return err
+	}
+	if _, err := gz.Write(body); err != nil {
+		return err
+	}
+	gz.Write([]byte("\r\n\r\n"))
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	_, err := w.file.Write(buf.Bytes())
+	return err
+}
+
+// IndexLocation is the subset of a Common Crawl CDX record that
+// FetchAndCopyWARCRecord needs to range-fetch a page's original response
+// record: which WARC file it lives in, and where. Callers that already
+// have their own CDX record type (e.g. cclinks' CCIndexRecord) populate
+// one of these from it rather than this package depending on theirs.
+type IndexLocation struct {
+	Filename string `json:"filename"`
+	Offset   string `json:"offset"`
+	Length   string `json:"length"`
+}
+
+// FetchAndCopyWARCRecord range-fetches filename[offset:offset+length-1]
+// from data.commoncrawl.org and returns the raw gzip-compressed record
+// bytes plus the WARC-Record-ID parsed out of its header, so the caller
+// can copy the record unchanged into a new WARC file while still being
+// able to point a metadata record back at it.
+func FetchAndCopyWARCRecord(client *http.Client, filename string, offset, length int64) (raw []byte, recordID string, err error) {
+	url := fmt.Sprintf("https://data.commoncrawl.org/%s", filename)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 && resp.StatusCode != 206 {
+		return nil, "", fmt.Errorf("http %d", resp.StatusCode)
+	}
+
+	raw, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, "", fmt.Errorf("not a valid gzip WARC record: %w", err)
+	}
+	defer gz.Close()
+
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "WARC-Record-ID:") {
+			recordID = strings.TrimSpace(strings.TrimPrefix(line, "WARC-Record-ID:"))
+		}
+	}
+
+	return raw, recordID, nil
+}
+
+// QueryCCIndexExact looks up the single CDX record for an exact page URL,
+// so --warc-output can find the WARC filename/offset/length to
+// range-fetch.
+func QueryCCIndexExact(client *http.Client, pageURL, archive string) (*IndexLocation, error) {
+	url := fmt.Sprintf("https://index.commoncrawl.org/%s-index?url=%s&matchType=exact&output=json&fl=url,filename,offset,length&limit=1",
+		archive, pageURL)
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	if scanner.Scan() {
+		var loc IndexLocation
+		if err := json.Unmarshal(scanner.Bytes(), &loc); err == nil {
+			return &loc, nil
+		}
+	}
+	return nil, fmt.Errorf("no CDX record found for %s", pageURL)
+}
@@ -2,10 +2,12 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"net/url"
 	"os"
 	"regexp"
@@ -16,6 +18,15 @@ import (
 	"time"
 
 	"github.com/gocolly/colly/v2"
+
+	"jester/scraping/go/pkg/frontier"
+	"jester/scraping/go/pkg/ratelimit"
+	"jester/scraping/go/pkg/render"
+	"jester/scraping/go/pkg/robots"
+	"jester/scraping/go/pkg/scope"
+	"jester/scraping/go/pkg/sources"
+
+	"golang.org/x/net/publicsuffix"
 )
 
 // CrawlConfig defines configuration for the crawler
@@ -29,6 +40,67 @@ type CrawlConfig struct {
 	URLKeywords      []string `json:"url_keywords"`
 	OutputFormat     string   `json:"output_format"`
 	DetectJSRequired bool     `json:"detect_js_required"`
+
+	// MaxDepth bounds how many hops of primary (same-scope <a href>) links
+	// the crawler follows past the seed URLs; 0 means "seeds only", the
+	// tool's original one-shot behavior, matching rod_crawler's --max-depth
+	// convention.
+	MaxDepth int `json:"max_depth"`
+	// Follow selects which link tags the crawler acts on: "primary"
+	// recurses into same-scope <a href> links up to MaxDepth but skips
+	// page assets, "related" lists a page's <img>/<link>/<script>/CSS
+	// assets in CrawlResult.Related but never recurses, "all" does both
+	// (the default). The listed assets are not fetched themselves; only
+	// linked .css files are, to scan them for url(...) references.
+	Follow       string   `json:"follow"`
+	ScopeHosts   []string `json:"scope_hosts"`
+	ScopeRegex   []string `json:"scope_regex"`
+	ExcludeRegex []string `json:"exclude_regex"`
+
+	// Render controls the JS-rendering fallback: "auto" hands any response
+	// that trips needsJSRendering to a render.Renderer and re-extracts
+	// content/links from the rendered DOM; "" (the default) leaves NeedsJS
+	// pages exactly as the static fetch returned them.
+	Render           string `json:"render"`
+	RenderWorkers    int    `json:"render_workers"`
+	RenderTimeoutSec int    `json:"render_timeout_sec"`
+
+	// OtherSources names third-party providers (wayback, commoncrawl, crt)
+	// queried for each seed URL's domain before the live crawl begins, so
+	// the crawler doubles as a recon tool without external glue scripts.
+	OtherSources []string `json:"other_sources"`
+	IncludeSubs  bool     `json:"include_subs"`
+
+	// PerHostRPS/MaxPerHostRPS/HostErrorBudget configure the adaptive
+	// per-host rate limiter (see pkg/ratelimit) that replaces the old flat
+	// colly.LimitRule{DomainGlob:"*"}.
+	PerHostRPS      float64 `json:"per_host_rps"`
+	MaxPerHostRPS   float64 `json:"max_per_host_rps"`
+	HostErrorBudget int     `json:"host_error_budget"`
+	StatsAddr       string  `json:"stats_addr"`
+
+	// RespectRobots/RobotsUserAgent/SeedFromSitemaps control robots.txt and
+	// sitemap.xml awareness (see pkg/robots).
+	RespectRobots    bool   `json:"respect_robots"`
+	RobotsUserAgent  string `json:"robots_user_agent"`
+	SeedFromSitemaps bool   `json:"seed_from_sitemaps"`
+}
+
+// rendersAuto reports whether config.Render asks processResponse to render
+// NeedsJS pages rather than just flagging them.
+func (c CrawlConfig) rendersAuto() bool {
+	return c.Render == "auto"
+}
+
+// followsPrimary/followsRelated report which link tags config.Follow
+// enables; an unrecognized value behaves like "all" rather than silently
+// crawling nothing.
+func (c CrawlConfig) followsPrimary() bool {
+	return c.Follow == "" || c.Follow == "primary" || c.Follow == "all"
+}
+
+func (c CrawlConfig) followsRelated() bool {
+	return c.Follow == "" || c.Follow == "related" || c.Follow == "all"
 }
 
 // OutlinkRecord represents an extracted outlink
@@ -42,17 +114,22 @@ type OutlinkRecord struct {
 
 // CrawlResult represents the result of crawling a single URL
 type CrawlResult struct {
-	URL           string          `json:"url"`
-	StatusCode    int             `json:"status_code"`
-	ContentType   string          `json:"content_type"`
-	Title         string          `json:"title"`
-	Content       string          `json:"content"`
-	HTML          string          `json:"html,omitempty"`
-	Outlinks      []OutlinkRecord `json:"outlinks"`
-	InternalLinks []string        `json:"internal_links"`
-	NeedsJS       bool            `json:"needs_js"`
-	Error         string          `json:"error,omitempty"`
-	LatencyMs     int64           `json:"latency_ms"`
+	URL           string                 `json:"url"`
+	StatusCode    int                    `json:"status_code"`
+	ContentType   string                 `json:"content_type"`
+	Title         string                 `json:"title"`
+	Content       string                 `json:"content"`
+	HTML          string                 `json:"html,omitempty"`
+	RawHTML       string                 `json:"raw_html,omitempty"`
+	RenderedBy    string                 `json:"rendered_by,omitempty"`
+	Source        string                 `json:"source,omitempty"`
+	LastMod       string                 `json:"last_mod,omitempty"`
+	Outlinks      []OutlinkRecord        `json:"outlinks"`
+	InternalLinks []string               `json:"internal_links"`
+	Related       []scope.ResourceRecord `json:"related,omitempty"`
+	NeedsJS       bool                   `json:"needs_js"`
+	Error         string                 `json:"error,omitempty"`
+	LatencyMs     int64                  `json:"latency_ms"`
 }
 
 // CrawlStats tracks crawl statistics
@@ -130,9 +207,46 @@ func printUsage() {
 	fmt.Println("  --user-agent=UA        Custom user agent")
 	fmt.Println("  --country-tlds=TLDS    Filter outlinks to these TLDs (.uk,.fr)")
 	fmt.Println("  --url-keywords=KW      Filter outlinks containing keywords")
-	fmt.Println("  --format=FMT           Output format: json, ndjson (default: ndjson)")
+	fmt.Println("  --format=FMT           Output format: json, ndjson, warc (default: ndjson)")
+	fmt.Println("                         warc writes request/response records per ISO 28500;")
+	fmt.Println("                         --output ending in .gz gzip-frames each record")
 	fmt.Println("  --detect-js            Detect pages needing JS rendering (default: true)")
 	fmt.Println("  --include-html         Include raw HTML in output")
+	fmt.Println("  --resume=FILE          Disk-backed frontier db; reopen an existing one to")
+	fmt.Println("                         pick a crashed/interrupted crawl back up")
+	fmt.Println("  --max-depth=N          Recurse into same-scope <a href> links N hops past")
+	fmt.Println("                         the seed URLs (default: 0, seeds only)")
+	fmt.Println("  --follow=MODE          Which link tags to act on: primary, related, all")
+	fmt.Println("                         (default: all)")
+	fmt.Println("  --scope-host=HOSTS     Comma-separated hosts (and their subdomains) primary")
+	fmt.Println("                         links must stay within")
+	fmt.Println("  --scope-regex=RE       Comma-separated regexes a primary link must match")
+	fmt.Println("  --exclude-regex=RE     Comma-separated regexes that reject a primary link")
+	fmt.Println("  --render=auto          Re-render pages that need JS via a headless Chrome")
+	fmt.Println("                         pool and re-extract content/links from the result")
+	fmt.Println("                         (default: off, pages are only flagged needs_js)")
+	fmt.Println("  --render-workers=N     Concurrent render tabs (default: 4)")
+	fmt.Println("  --render-timeout=SEC   Per-page render timeout in seconds (default: 15)")
+	fmt.Println("  --other-source=SRCS    Comma-separated third-party seed providers to query")
+	fmt.Println("                         before crawling each seed URL's domain: wayback,")
+	fmt.Println("                         commoncrawl, crt")
+	fmt.Println("  --include-subs         Keep off-apex hosts --other-source discovers")
+	fmt.Println("                         (default: apex domain only)")
+	fmt.Println("  --per-host-rps=N       Starting requests/sec per registrable domain")
+	fmt.Println("                         (default: 2); halved on 429/503, recovered by")
+	fmt.Println("                         +1 rps per 30s clean")
+	fmt.Println("  --max-per-host-rps=N   Ceiling a host's rate can recover to (default: 16x")
+	fmt.Println("                         --per-host-rps)")
+	fmt.Println("  --host-error-budget=K  Stop sending a host requests after K consecutive")
+	fmt.Println("                         429/503/errors (default: 0, never drop)")
+	fmt.Println("  --stats-addr=HOST:PORT Serve per-host rate-limiter stats as JSON")
+	fmt.Println("  --respect-robots=BOOL  Honor robots.txt Disallow/Allow/Crawl-delay for")
+	fmt.Println("                         --user-agent (default: true)")
+	fmt.Println("  --robots-user-agent=UA User-agent to match against robots.txt groups")
+	fmt.Println("                         (default: --user-agent)")
+	fmt.Println("  --seed-from-sitemaps   Discover each seed domain's sitemap(s) via robots.txt")
+	fmt.Println("                         (or /sitemap.xml, /sitemap_index.xml) and seed every")
+	fmt.Println("                         <loc> they list, recursing into sitemap indexes")
 	fmt.Println()
 	fmt.Println("TEST USAGE:")
 	fmt.Println("  ./colly_crawler test <URL>")
@@ -182,7 +296,7 @@ func handleCrawlCommand() {
 	}
 
 	// Run crawler
-	stats := crawlURLs(urls, config, output)
+	stats := crawlURLs(urls, config, output, outputFile)
 
 	// Print stats
 	fmt.Fprintf(os.Stderr, "\n✅ Crawl completed!\n")
@@ -222,6 +336,8 @@ func parseCrawlArgs() CrawlConfig {
 		UserAgent:        "Mozilla/5.0 (compatible; JESTER/1.5; +https://drill-search.com/bot)",
 		OutputFormat:     "ndjson",
 		DetectJSRequired: true,
+		Follow:           "all",
+		RespectRobots:    true,
 	}
 
 	// Parse URL file
@@ -275,6 +391,73 @@ func parseCrawlArgs() CrawlConfig {
 		config.DetectJSRequired = false
 	}
 
+	// Parse recursive-crawl scope
+	if d := getArgValue("--max-depth"); d != "" {
+		if val, err := strconv.Atoi(d); err == nil {
+			config.MaxDepth = val
+		}
+	}
+	if f := getArgValue("--follow"); f != "" {
+		config.Follow = f
+	}
+	if h := getArgValue("--scope-host"); h != "" {
+		config.ScopeHosts = strings.Split(h, ",")
+	}
+	if re := getArgValue("--scope-regex"); re != "" {
+		config.ScopeRegex = strings.Split(re, ",")
+	}
+	if re := getArgValue("--exclude-regex"); re != "" {
+		config.ExcludeRegex = strings.Split(re, ",")
+	}
+
+	// Parse render fallback
+	config.Render = getArgValue("--render")
+	config.RenderWorkers = 4
+	if w := getArgValue("--render-workers"); w != "" {
+		if val, err := strconv.Atoi(w); err == nil {
+			config.RenderWorkers = val
+		}
+	}
+	config.RenderTimeoutSec = 15
+	if t := getArgValue("--render-timeout"); t != "" {
+		if val, err := strconv.Atoi(t); err == nil {
+			config.RenderTimeoutSec = val
+		}
+	}
+
+	// Parse third-party seed sources
+	if s := getArgValue("--other-source"); s != "" {
+		config.OtherSources = strings.Split(s, ",")
+	}
+	config.IncludeSubs = hasArg("--include-subs")
+
+	// Parse adaptive per-host rate limiting
+	config.PerHostRPS = 2
+	if v := getArgValue("--per-host-rps"); v != "" {
+		if val, err := strconv.ParseFloat(v, 64); err == nil {
+			config.PerHostRPS = val
+		}
+	}
+	config.MaxPerHostRPS = config.PerHostRPS * 16
+	if v := getArgValue("--max-per-host-rps"); v != "" {
+		if val, err := strconv.ParseFloat(v, 64); err == nil {
+			config.MaxPerHostRPS = val
+		}
+	}
+	if v := getArgValue("--host-error-budget"); v != "" {
+		if val, err := strconv.Atoi(v); err == nil {
+			config.HostErrorBudget = val
+		}
+	}
+	config.StatsAddr = getArgValue("--stats-addr")
+
+	// Parse robots.txt/sitemap awareness
+	if v := getArgValue("--respect-robots"); v != "" {
+		config.RespectRobots = v != "false"
+	}
+	config.RobotsUserAgent = getArgValue("--robots-user-agent")
+	config.SeedFromSitemaps = hasArg("--seed-from-sitemaps")
+
 	return config
 }
 
@@ -321,19 +504,101 @@ func loadURLs(sources []string) ([]string, error) {
 	return urls, nil
 }
 
-func crawlURLs(urls []string, config CrawlConfig, output io.Writer) CrawlStats {
+func crawlURLs(urls []string, config CrawlConfig, output io.Writer, outputPath string) CrawlStats {
 	var stats CrawlStats
-	stats.Total = int64(len(urls))
 
 	startTime := time.Now()
 
+	// Frontier is the disk-backed, resumable replacement for queuing urls
+	// straight into an in-memory wg/c.Visit loop: --resume=state.db reopens
+	// the same bbolt file a previous (possibly crashed) run used, so
+	// already-seen URLs aren't re-pushed and whatever was still pending
+	// picks back up rather than being lost.
+	frontierPath := getArgValue("--resume")
+	resuming := false
+	if frontierPath == "" {
+		tmp, err := os.CreateTemp("", "colly-frontier-*.db")
+		if err != nil {
+			log.Fatalf("Failed to create temp frontier db: %v", err)
+		}
+		frontierPath = tmp.Name()
+		tmp.Close()
+		defer os.Remove(frontierPath)
+	} else if _, err := os.Stat(frontierPath); err == nil {
+		resuming = true
+	}
+
+	fr, err := frontier.Open(frontierPath, 100)
+	if err != nil {
+		log.Fatalf("Failed to open frontier: %v", err)
+	}
+	defer fr.Close()
+
+	if !resuming {
+		for _, u := range urls {
+			if err := fr.Push(u, 0, "", scope.LinkPrimary); err != nil {
+				log.Fatalf("Failed to seed frontier: %v", err)
+			}
+		}
+
+		if len(config.OtherSources) > 0 {
+			seedFromOtherSources(fr, urls, config)
+		}
+
+		if config.SeedFromSitemaps {
+			seedFromSitemaps(fr, urls)
+		}
+	} else {
+		fmt.Fprintf(os.Stderr, "Resuming from %s: %d URLs still pending\n", frontierPath, fr.Len())
+	}
+	stats.Total = int64(fr.Len())
+
+	policy := buildScopePolicy(config)
+
+	// depths tracks the frontier depth each in-flight URL was popped at, so
+	// OnResponse knows whether it's still allowed to enqueue that page's
+	// primary links for another hop.
+	depths := make(map[string]int)
+	var depthMu sync.Mutex
+	var inFlight int64
+
+	// sourceTags records which third-party provider (if any) surfaced a
+	// pending URL, so OnResponse can stamp CrawlResult.Source once it's
+	// popped and visited.
+	sourceTags := make(map[string]string)
+	var sourceMu sync.Mutex
+
+	// lastMods records a sitemap-seeded URL's <lastmod>, so OnResponse can
+	// stamp CrawlResult.LastMod once it's popped and visited.
+	lastMods := make(map[string]string)
+	var lastModMu sync.Mutex
+
+	// robotsCache enforces robots.txt (Disallow/Allow/Crawl-delay) per host
+	// for the configured user-agent; nil (--respect-robots=false) leaves
+	// every URL unchecked.
+	var robotsCache *robots.Cache
+	robotsUA := config.RobotsUserAgent
+	if robotsUA == "" {
+		robotsUA = config.UserAgent
+	}
+	if config.RespectRobots {
+		robotsCache = robots.NewCache(time.Hour, 1000, &http.Client{Timeout: 10 * time.Second})
+	}
+	// crawlDelayUntil tracks, per host, the earliest time the next request
+	// may fire when robots.txt specifies a Crawl-delay for robotsUA.
+	crawlDelayUntil := make(map[string]time.Time)
+	var crawlDelayMu sync.Mutex
+
 	// Create collector
 	c := colly.NewCollector(
 		colly.Async(true),
 		colly.UserAgent(config.UserAgent),
 	)
 
-	// Set limits
+	// Set limits. Parallelism here is just an overall worker-pool ceiling;
+	// actual per-host pacing is now the adaptive token bucket below, which
+	// reacts to 429/503/Retry-After instead of treating every domain the
+	// same way a flat LimitRule does.
 	c.Limit(&colly.LimitRule{
 		DomainGlob:  "*",
 		Parallelism: config.MaxConcurrent,
@@ -343,6 +608,11 @@ func crawlURLs(urls []string, config CrawlConfig, output io.Writer) CrawlStats {
 	// Set timeout
 	c.SetRequestTimeout(time.Duration(config.RequestTimeout) * time.Second)
 
+	rl := ratelimit.NewManager(config.PerHostRPS, config.MaxPerHostRPS, config.HostErrorBudget)
+	if config.StatsAddr != "" {
+		startRateLimitStatsServer(config.StatsAddr, rl)
+	}
+
 	// Results channel for ordered output
 	results := make(chan CrawlResult, 1000)
 	var wg sync.WaitGroup
@@ -350,6 +620,25 @@ func crawlURLs(urls []string, config CrawlConfig, output io.Writer) CrawlStats {
 	var writeMutex sync.Mutex
 	includeHTML := hasArg("--include-html")
 
+	var warcWriter *WarcWriter
+	if config.OutputFormat == "warc" {
+		var err error
+		warcWriter, err = NewWarcWriter(output, strings.HasSuffix(outputPath, ".gz"))
+		if err != nil {
+			log.Fatalf("Failed to start WARC output: %v", err)
+		}
+	}
+
+	// One shared ChromeDPRenderer pool serves every OnResponse callback for
+	// this run when --render=auto is set; Noop otherwise, so the common
+	// case never touches chromedp at all.
+	var renderer render.Renderer = render.Noop{}
+	if config.rendersAuto() {
+		chromeRenderer := render.NewChromeDPRenderer(config.RenderWorkers, "")
+		defer chromeRenderer.Close()
+		renderer = chromeRenderer
+	}
+
 	// Writer goroutine
 	writerWg.Add(1)
 	go func() {
@@ -360,6 +649,13 @@ func crawlURLs(urls []string, config CrawlConfig, output io.Writer) CrawlStats {
 		}
 
 		for result := range results {
+			if config.OutputFormat == "warc" {
+				// WARC records are written directly off colly.Response in
+				// OnResponse (it carries the raw headers/body this CrawlResult
+				// has already lost); this loop only drains results for stats.
+				continue
+			}
+
 			writeMutex.Lock()
 			var resultBytes []byte
 			var err error
@@ -392,8 +688,33 @@ func crawlURLs(urls []string, config CrawlConfig, output io.Writer) CrawlStats {
 	startTimes := make(map[string]time.Time)
 	var startTimeMutex sync.Mutex
 
-	// OnRequest - record start time
+	// OnRequest - throttle per-host via the adaptive rate limiter, then
+	// record start time. A dropped host (past --host-error-budget) aborts
+	// the request outright rather than ever reaching the network. robots.txt
+	// Crawl-delay is also honored here rather than in the sequential pump
+	// loop below: OnRequest runs per-request on colly's own async workers,
+	// so blocking here only paces requests to this one host and never
+	// stalls dispatch of URLs to every other host in the crawl.
 	c.OnRequest(func(r *colly.Request) {
+		if err := rl.Acquire(r.URL.Host); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			r.Abort()
+			return
+		}
+
+		if robotsCache != nil {
+			if delay, ok := robotsCache.CrawlDelay(context.Background(), robotsUA, r.URL.String()); ok {
+				crawlDelayMu.Lock()
+				if wait, due := crawlDelayUntil[r.URL.Host]; due && time.Now().Before(wait) {
+					crawlDelayMu.Unlock()
+					time.Sleep(time.Until(wait))
+					crawlDelayMu.Lock()
+				}
+				crawlDelayUntil[r.URL.Host] = time.Now().Add(delay)
+				crawlDelayMu.Unlock()
+			}
+		}
+
 		startTimeMutex.Lock()
 		startTimes[r.URL.String()] = time.Now()
 		startTimeMutex.Unlock()
@@ -401,6 +722,9 @@ func crawlURLs(urls []string, config CrawlConfig, output io.Writer) CrawlStats {
 
 	// OnResponse - process successful responses
 	c.OnResponse(func(r *colly.Response) {
+		rl.Release(r.Request.URL.Host)
+		rl.Report(r.Request.URL.Host, r.StatusCode, r.Headers.Get("Retry-After"))
+
 		startTimeMutex.Lock()
 		startTime := startTimes[r.Request.URL.String()]
 		delete(startTimes, r.Request.URL.String())
@@ -408,25 +732,71 @@ func crawlURLs(urls []string, config CrawlConfig, output io.Writer) CrawlStats {
 
 		latency := time.Since(startTime).Milliseconds()
 
-		result := processResponse(r, config, includeHTML)
+		result := processResponse(r, config, includeHTML, renderer)
 		result.LatencyMs = latency
 
+		sourceMu.Lock()
+		result.Source = sourceTags[r.Request.URL.String()]
+		delete(sourceTags, r.Request.URL.String())
+		sourceMu.Unlock()
+
+		lastModMu.Lock()
+		result.LastMod = lastMods[r.Request.URL.String()]
+		delete(lastMods, r.Request.URL.String())
+		lastModMu.Unlock()
+
+		if warcWriter != nil {
+			if err := warcWriter.WriteRecords(r); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to write WARC record for %s: %v\n", result.URL, err)
+			}
+		}
+
 		if result.NeedsJS {
 			atomic.AddInt64(&stats.NeedsJS, 1)
 		}
 		atomic.AddInt64(&stats.Success, 1)
 
+		depthMu.Lock()
+		depth := depths[r.Request.URL.String()]
+		delete(depths, r.Request.URL.String())
+		depthMu.Unlock()
+
+		if config.followsPrimary() && depth < config.MaxDepth {
+			for _, link := range result.InternalLinks {
+				linkURL, err := url.Parse(link)
+				if err != nil || !policy.InScope(r.Request.URL, linkURL) || !inScopeHost(linkURL.Host, config.ScopeHosts) {
+					continue
+				}
+				if err := fr.Push(link, depth+1, r.Request.URL.String(), scope.LinkPrimary); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to enqueue %s: %v\n", link, err)
+				}
+			}
+		}
+
 		results <- result
 		wg.Done()
+		atomic.AddInt64(&inFlight, -1)
 	})
 
 	// OnError - handle failures
 	c.OnError(func(r *colly.Response, err error) {
 		startTimeMutex.Lock()
-		startTime := startTimes[r.Request.URL.String()]
+		startTime, acquired := startTimes[r.Request.URL.String()]
 		delete(startTimes, r.Request.URL.String())
 		startTimeMutex.Unlock()
 
+		// acquired is false when OnRequest aborted the request before ever
+		// taking a rate-limit token (a dropped host), so there's no token
+		// to release and no response to report back to the limiter.
+		if acquired {
+			rl.Release(r.Request.URL.Host)
+			var retryAfter string
+			if r.Headers != nil {
+				retryAfter = r.Headers.Get("Retry-After")
+			}
+			rl.Report(r.Request.URL.Host, r.StatusCode, retryAfter)
+		}
+
 		latency := time.Since(startTime).Milliseconds()
 
 		result := CrawlResult{
@@ -436,16 +806,65 @@ func crawlURLs(urls []string, config CrawlConfig, output io.Writer) CrawlStats {
 			LatencyMs:  latency,
 		}
 
+		depthMu.Lock()
+		delete(depths, r.Request.URL.String())
+		depthMu.Unlock()
+
+		sourceMu.Lock()
+		result.Source = sourceTags[r.Request.URL.String()]
+		delete(sourceTags, r.Request.URL.String())
+		sourceMu.Unlock()
+
+		lastModMu.Lock()
+		result.LastMod = lastMods[r.Request.URL.String()]
+		delete(lastMods, r.Request.URL.String())
+		lastModMu.Unlock()
+
 		atomic.AddInt64(&stats.Failed, 1)
 
 		results <- result
 		wg.Done()
+		atomic.AddInt64(&inFlight, -1)
 	})
 
-	// Queue all URLs
-	for _, u := range urls {
-		wg.Add(1)
-		c.Visit(u)
+	// Pump the frontier into colly: unlike the original static urls-slice
+	// loop, the frontier can grow mid-crawl as OnResponse enqueues a page's
+	// in-scope primary links, so draining it once up front isn't enough —
+	// keep popping until nothing is pending AND nothing already popped is
+	// still in flight (which itself might still enqueue more).
+	for {
+		item, ok := fr.Pop()
+		if ok {
+			if robotsCache != nil && !robotsCache.Allowed(context.Background(), robotsUA, item.URL) {
+				fmt.Fprintf(os.Stderr, "Skipping %s: disallowed by robots.txt\n", item.URL)
+				continue
+			}
+
+			depthMu.Lock()
+			depths[item.URL] = item.Depth
+			depthMu.Unlock()
+
+			if item.Source != "" {
+				sourceMu.Lock()
+				sourceTags[item.URL] = item.Source
+				sourceMu.Unlock()
+			}
+
+			if item.LastMod != "" {
+				lastModMu.Lock()
+				lastMods[item.URL] = item.LastMod
+				lastModMu.Unlock()
+			}
+
+			atomic.AddInt64(&inFlight, 1)
+			wg.Add(1)
+			c.Visit(item.URL)
+			continue
+		}
+		if atomic.LoadInt64(&inFlight) == 0 && fr.Len() == 0 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
 	}
 
 	// Wait for all requests to complete
@@ -461,6 +880,116 @@ func crawlURLs(urls []string, config CrawlConfig, output io.Writer) CrawlStats {
 	return stats
 }
 
+// startRateLimitStatsServer serves rl's per-host stats as JSON at
+// --stats-addr for the life of the process; there's no graceful shutdown
+// since the crawl itself ends the process when it's done, matching
+// cclinks' --dashboard server.
+func startRateLimitStatsServer(addr string, rl *ratelimit.Manager) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rl.Stats())
+	})
+
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: stats server stopped: %v\n", err)
+		}
+	}()
+	fmt.Fprintf(os.Stderr, "Rate-limit stats listening on http://%s\n", addr)
+}
+
+// seedFromOtherSources queries config.OtherSources for every distinct
+// registrable domain among urls and pushes whatever they return into fr
+// ahead of the live crawl, tagged with the provider's name.
+func seedFromOtherSources(fr *frontier.Frontier, urls []string, config CrawlConfig) {
+	resolved := sources.Resolve(config.OtherSources, &http.Client{Timeout: 30 * time.Second}, func(format string, args ...interface{}) {
+		fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
+	})
+	if len(resolved) == 0 {
+		return
+	}
+
+	ctx := context.Background()
+	for _, domain := range seedDomains(urls) {
+		for _, src := range resolved {
+			found, err := src.Fetch(ctx, domain, config.IncludeSubs)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", src.Name(), err)
+				continue
+			}
+			for _, u := range found {
+				if err := fr.PushSourced(u, 0, "", scope.LinkPrimary, src.Name()); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to enqueue %s: %v\n", u, err)
+				}
+			}
+			fmt.Fprintf(os.Stderr, "%s: %d URLs for %s\n", src.Name(), len(found), domain)
+		}
+	}
+}
+
+// seedFromSitemaps discovers each distinct host among urls' sitemap(s) —
+// via robots.txt Sitemap: directives, falling back to /sitemap.xml and
+// /sitemap_index.xml — and pushes every <loc> they list (recursing into
+// sitemap indexes) into fr, tagged source "sitemap" with its <lastmod>
+// retained.
+func seedFromSitemaps(fr *frontier.Frontier, urls []string) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	rc := robots.NewCache(time.Hour, 1000, client)
+	ctx := context.Background()
+
+	seen := map[string]bool{}
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" || seen[u.Host] {
+			continue
+		}
+		seen[u.Host] = true
+
+		robotsSitemaps, _ := rc.Sitemaps(ctx, raw)
+		for _, sitemapURL := range robots.DiscoverSitemapURLs(robotsSitemaps, u.Scheme, u.Host) {
+			entries, err := robots.FetchSitemap(ctx, client, sitemapURL)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: sitemap %s: %v\n", sitemapURL, err)
+				continue
+			}
+			for _, e := range entries {
+				if e.Loc == "" {
+					continue
+				}
+				if err := fr.PushFull(e.Loc, 0, "", scope.LinkPrimary, "sitemap", e.LastMod); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to enqueue %s: %v\n", e.Loc, err)
+				}
+			}
+			fmt.Fprintf(os.Stderr, "sitemap: %d URLs from %s\n", len(entries), sitemapURL)
+		}
+	}
+}
+
+// seedDomains returns the distinct registrable domains (eTLD+1) among
+// urls, so seedFromOtherSources queries each third-party source once per
+// site rather than once per seed URL.
+func seedDomains(urls []string) []string {
+	seen := map[string]bool{}
+	var domains []string
+	for _, u := range urls {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			continue
+		}
+		domain, err := publicsuffix.EffectiveTLDPlusOne(parsed.Hostname())
+		if err != nil {
+			domain = parsed.Hostname()
+		}
+		if domain == "" || seen[domain] {
+			continue
+		}
+		seen[domain] = true
+		domains = append(domains, domain)
+	}
+	return domains
+}
+
 func crawlSingleURL(targetURL string, config CrawlConfig, includeHTML bool) CrawlResult {
 	var result CrawlResult
 	result.URL = targetURL
@@ -473,8 +1002,15 @@ func crawlSingleURL(targetURL string, config CrawlConfig, includeHTML bool) Craw
 
 	c.SetRequestTimeout(time.Duration(config.RequestTimeout) * time.Second)
 
+	var renderer render.Renderer = render.Noop{}
+	if config.rendersAuto() {
+		chromeRenderer := render.NewChromeDPRenderer(config.RenderWorkers, "")
+		defer chromeRenderer.Close()
+		renderer = chromeRenderer
+	}
+
 	c.OnResponse(func(r *colly.Response) {
-		result = processResponse(r, config, includeHTML)
+		result = processResponse(r, config, includeHTML, renderer)
 	})
 
 	c.OnError(func(r *colly.Response, err error) {
@@ -492,7 +1028,7 @@ func crawlSingleURL(targetURL string, config CrawlConfig, includeHTML bool) Craw
 	return result
 }
 
-func processResponse(r *colly.Response, config CrawlConfig, includeHTML bool) CrawlResult {
+func processResponse(r *colly.Response, config CrawlConfig, includeHTML bool, renderer render.Renderer) CrawlResult {
 	result := CrawlResult{
 		URL:         r.Request.URL.String(),
 		StatusCode:  r.StatusCode,
@@ -525,11 +1061,51 @@ func processResponse(r *colly.Response, config CrawlConfig, includeHTML bool) Cr
 	result.Outlinks = outlinks
 	result.InternalLinks = internalLinks
 
+	// Related assets (images, stylesheets, scripts, CSS url(...) refs) are
+	// always collected for an in-scope page regardless of --max-depth, one
+	// hop out, unless --follow excludes them.
+	if config.followsRelated() {
+		result.Related = collectRelated(html, baseURL)
+	}
+
 	// Detect if JS rendering is needed
 	if config.DetectJSRequired {
 		result.NeedsJS = needsJSRendering(html, textContent)
 	}
 
+	// --render=auto hands NeedsJS pages to the renderer and re-extracts
+	// everything from the rendered DOM, so downstream consumers see real
+	// content/outlinks instead of an empty SPA shell. The static fetch's
+	// HTML is kept as RawHTML (when --include-html is set) so a caller can
+	// still tell what the server actually sent versus what Chrome produced.
+	if result.NeedsJS && config.rendersAuto() {
+		timeout := time.Duration(config.RenderTimeoutSec) * time.Second
+		rendered, err := renderer.Render(context.Background(), result.URL, timeout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: render failed for %s: %v\n", result.URL, err)
+		} else {
+			if includeHTML {
+				result.RawHTML = html
+				result.HTML = rendered.HTML
+			}
+			result.RenderedBy = rendered.RenderedBy
+
+			renderedText := extractTextContent(rendered.HTML)
+			if len(renderedText) > 10000 {
+				renderedText = renderedText[:10000]
+			}
+			result.Content = renderedText
+
+			outlinks, internalLinks := extractLinks(rendered.HTML, baseURL, config)
+			result.Outlinks = outlinks
+			result.InternalLinks = internalLinks
+
+			if config.followsRelated() {
+				result.Related = collectRelated(rendered.HTML, baseURL)
+			}
+		}
+	}
+
 	return result
 }
 
@@ -593,8 +1169,10 @@ func extractLinks(html string, baseURL *url.URL, config CrawlConfig) ([]OutlinkR
 			continue
 		}
 
-		// Check if external
-		isExternal := linkURL.Host != baseURL.Host
+		// Check if external by registrable domain (eTLD+1) rather than an
+		// exact host match, so e.g. "www.example.com" and "blog.example.com"
+		// are correctly treated as the same site.
+		isExternal := !scope.SameRegistrableDomain(linkURL.Host, baseURL.Host)
 
 		// Check nofollow
 		isNoFollow := nofollowRegex.MatchString(fullTag)
@@ -653,6 +1231,81 @@ func shouldIncludeOutlink(linkURL *url.URL, config CrawlConfig) bool {
 	return true
 }
 
+// buildScopePolicy turns --scope-regex/--exclude-regex into a scope.Policy.
+// Restricting primary-link following to the seed's own registrable domain
+// is the default, but an explicit --scope-host list takes over that job
+// instead (inScopeHost), so SameRegistrableDomain is dropped in that case.
+func buildScopePolicy(config CrawlConfig) scope.Policy {
+	return scope.NewPolicy(nil, len(config.ScopeHosts) == 0, config.ScopeRegex, config.ExcludeRegex)
+}
+
+// inScopeHost reports whether host is covered by --scope-host (an exact
+// host or one of its subdomains); an empty scopeHosts list means every
+// host passes, leaving scope.Policy as the only gate.
+func inScopeHost(host string, scopeHosts []string) bool {
+	if len(scopeHosts) == 0 {
+		return true
+	}
+	host = strings.ToLower(host)
+	for _, h := range scopeHosts {
+		h = strings.ToLower(h)
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// relatedHTTPClient fetches the handful of linked .css files a page's
+// related resources point at; a short timeout keeps one slow stylesheet
+// from holding up the worker crawling the next page.
+var relatedHTTPClient = &http.Client{Timeout: 5 * time.Second}
+
+// collectRelated gathers <img src>, <link rel=stylesheet href>, <script
+// src>, and CSS url(...) references (from both inline <style> blocks and
+// linked .css files) from html, resolved against base. Linked .css files
+// are fetched at most once and are never themselves re-scanned past their
+// own url(...) references, keeping related-resource discovery to one hop
+// off the page regardless of --max-depth. Mirrors rod_crawler's
+// collectRelated, which shares the same scope.ParseRelated/ParseCSSURLs
+// helpers.
+func collectRelated(html string, base *url.URL) []scope.ResourceRecord {
+	related, inlineCSS := scope.ParseRelated(html, base)
+
+	for _, css := range inlineCSS {
+		related = append(related, scope.ParseCSSURLs(css, base)...)
+	}
+
+	fetched := make(map[string]bool)
+	for _, r := range related {
+		if !strings.HasSuffix(strings.ToLower(strings.SplitN(r.URL, "?", 2)[0]), ".css") {
+			continue
+		}
+		if fetched[r.URL] {
+			continue
+		}
+		fetched[r.URL] = true
+
+		resp, err := relatedHTTPClient.Get(r.URL)
+		if err != nil {
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			continue
+		}
+
+		cssBase, err := url.Parse(r.URL)
+		if err != nil {
+			continue
+		}
+		related = append(related, scope.ParseCSSURLs(string(body), cssBase)...)
+	}
+
+	return related
+}
+
 func needsJSRendering(html, textContent string) bool {
 	// Check for SPA indicators
 	for _, indicator := range spaIndicators {
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// WarcWriter appends WARC/1.1 records for a crawl run: one warcinfo record
+// up front, then a request/response pair per fetched URL, built from the
+// exact request Colly sent and the response it got back (as opposed to
+// rod_crawler's WarcWriter, which synthesizes a request line since the
+// browser does its own fetching under the hood). Concurrent OnResponse
+// callbacks all write through the same *WarcWriter, so every write is
+// serialized by mu. When gzipEach is set, each record is its own
+// independently-decompressable gzip member, keeping the file a valid
+// multi-member WARC.
+type WarcWriter struct {
+	w      io.Writer
+	gzip   bool
+	mu     sync.Mutex
+	offset int64
+}
+
+// NewWarcWriter wraps w (the crawl's --output file) as a WARC/1.1 writer,
+// gzip-framing each record individually when gzipEach is set (i.e.
+// --output ends in ".gz"), and immediately writes the run's warcinfo
+// record.
+func NewWarcWriter(w io.Writer, gzipEach bool) (*WarcWriter, error) {
+	warc := &WarcWriter{w: w, gzip: gzipEach}
+	if err := warc.writeWarcinfo(); err != nil {
+		return nil, err
+	}
+	return warc, nil
+}
+
+func newWarcRecordID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+func warcDate() string {
+	return time.Now().UTC().Format("2006-01-02T15:04:05Z")
+}
+
+// writeWarcinfo emits the one warcinfo record every WARC file should open
+// with, identifying the tool that produced it.
+func (w *WarcWriter) writeWarcinfo() error {
+	payload := "software: jester colly_crawler\r\nformat: WARC File Format 1.1\r\n"
+
+	var header strings.Builder
+	header.WriteString("WARC/1.1\r\n")
+	header.WriteString("WARC-Type: warcinfo\r\n")
+	header.WriteString(fmt.Sprintf("WARC-Record-ID: %s\r\n", newWarcRecordID()))
+	header.WriteString(fmt.Sprintf("WARC-Date: %s\r\n", warcDate()))
+	header.WriteString("Content-Type: application/warc-fields\r\n")
+	header.WriteString(fmt.Sprintf("Content-Length: %d\r\n", len(payload)))
+	header.WriteString("\r\n")
+
+	return w.writeMember(header.String(), payload)
+}
+
+// WriteRecords appends a request/response record pair for one crawled page
+// to the WARC file, in the request's original HTTP shape (request line,
+// headers, body).
+func (w *WarcWriter) WriteRecords(r *colly.Response) error {
+	targetURL := r.Request.URL.String()
+	date := warcDate()
+
+	requestID := newWarcRecordID()
+	responseID := newWarcRecordID()
+
+	var reqLine strings.Builder
+	method := r.Request.Method
+	if method == "" {
+		method = "GET"
+	}
+	reqLine.WriteString(fmt.Sprintf("%s %s HTTP/1.1\r\n", method, r.Request.URL.RequestURI()))
+	reqLine.WriteString(fmt.Sprintf("Host: %s\r\n", r.Request.URL.Host))
+	if r.Request.Headers != nil {
+		for name, values := range *r.Request.Headers {
+			for _, v := range values {
+				reqLine.WriteString(fmt.Sprintf("%s: %s\r\n", name, v))
+			}
+		}
+	}
+	reqLine.WriteString("\r\n")
+	reqPayload := reqLine.String()
+
+	var reqHeader strings.Builder
+	reqHeader.WriteString("WARC/1.1\r\n")
+	reqHeader.WriteString("WARC-Type: request\r\n")
+	reqHeader.WriteString(fmt.Sprintf("WARC-Record-ID: %s\r\n", requestID))
+	reqHeader.WriteString(fmt.Sprintf("WARC-Date: %s\r\n", date))
+	reqHeader.WriteString(fmt.Sprintf("WARC-Target-URI: %s\r\n", targetURL))
+	reqHeader.WriteString(fmt.Sprintf("WARC-Concurrent-To: %s\r\n", responseID))
+	reqHeader.WriteString("Content-Type: application/http; msgtype=request\r\n")
+	reqHeader.WriteString(fmt.Sprintf("Content-Length: %d\r\n", len(reqPayload)))
+	reqHeader.WriteString("\r\n")
+
+	if err := w.writeMember(reqHeader.String(), reqPayload); err != nil {
+		return err
+	}
+
+	sum := sha1.Sum(r.Body)
+	digest := base32.StdEncoding.EncodeToString(sum[:])
+
+	var statusLine strings.Builder
+	statusLine.WriteString(fmt.Sprintf("HTTP/1.1 %d %s\r\n", r.StatusCode, http.StatusText(r.StatusCode)))
+	if r.Headers != nil {
+		for name, values := range *r.Headers {
+			for _, v := range values {
+				statusLine.WriteString(fmt.Sprintf("%s: %s\r\n", name, v))
+			}
+		}
+	}
+	statusLine.WriteString("\r\n")
+	respPayload := statusLine.String() + string(r.Body)
+
+	var respHeader strings.Builder
+	respHeader.WriteString("WARC/1.1\r\n")
+	respHeader.WriteString("WARC-Type: response\r\n")
+	respHeader.WriteString(fmt.Sprintf("WARC-Record-ID: %s\r\n", responseID))
+	respHeader.WriteString(fmt.Sprintf("WARC-Date: %s\r\n", date))
+	respHeader.WriteString(fmt.Sprintf("WARC-Target-URI: %s\r\n", targetURL))
+	respHeader.WriteString(fmt.Sprintf("WARC-Concurrent-To: %s\r\n", requestID))
+	respHeader.WriteString(fmt.Sprintf("WARC-Payload-Digest: sha1:%s\r\n", digest))
+	respHeader.WriteString("Content-Type: application/http; msgtype=response\r\n")
+	respHeader.WriteString(fmt.Sprintf("Content-Length: %d\r\n", len(respPayload)))
+	respHeader.WriteString("\r\n")
+
+	return w.writeMember(respHeader.String(), respPayload)
+}
+
+// writeMember appends header+body as one WARC record, gzip-compressed as
+// its own member when w.gzip is set, serializing concurrent callers record
+// by record so no two records' bytes interleave. Another page's record can
+// still land between one page's request and response records.
+func (w *WarcWriter) writeMember(header, body string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	record := header + body + "\r\n\r\n"
+
+	var out []byte
+	if w.gzip {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write([]byte(record)); err != nil {
+			return err
+		}
+		if err := gz.Close(); err != nil {
+			return err
+		}
+		out = buf.Bytes()
+	} else {
+		out = []byte(record)
+	}
+
+	n, err := w.w.Write(out)
+	w.offset += int64(n)
+	return err
+}
@@ -0,0 +1,143 @@
+// tiercrawl is the adaptive-tier orchestrator the other crawlers' file
+// comments describe but never implemented: for each URL it runs
+// colly_crawler first, escalates to rod_crawler if tierrouter.Classify
+// says the page needs JS, and escalates further to an external Playwright
+// worker if Rod itself can't get past the page (repeated navigation
+// timeouts, or a detected anti-bot wall). A bbolt cache remembers which
+// tier last worked for a domain so later URLs on the same host skip
+// straight to it.
+//
+// Usage:
+//
+//	tiercrawl --urls=urls.txt --colly-bin=./colly_crawler --rod-bin=./rod_crawler \
+//	    --playwright-cmd="python3 playwright_worker.py" --tier-cache=tiers.db --tier-stats
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"jester/scraping/go/pkg/tierrouter"
+)
+
+func main() {
+	if len(os.Args) < 2 || hasArg("--help") {
+		printUsage()
+		return
+	}
+
+	urlsFile := getArgValue("--urls")
+	if urlsFile == "" {
+		fmt.Fprintln(os.Stderr, "Error: --urls=<file> is required")
+		os.Exit(1)
+	}
+
+	collyBin := getArgValue("--colly-bin")
+	if collyBin == "" {
+		collyBin = "./colly_crawler"
+	}
+	rodBin := getArgValue("--rod-bin")
+	if rodBin == "" {
+		rodBin = "./rod_crawler"
+	}
+	var playwrightCmd []string
+	if pw := getArgValue("--playwright-cmd"); pw != "" {
+		playwrightCmd = strings.Fields(pw)
+	}
+	tierCachePath := getArgValue("--tier-cache")
+	if tierCachePath == "" {
+		tierCachePath = "tiers.db"
+	}
+	showTierStats := hasArg("--tier-stats")
+
+	urls, err := readLines(urlsFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading --urls file: %v\n", err)
+		os.Exit(1)
+	}
+
+	cache, err := tierrouter.OpenTierCache(tierCachePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening tier cache: %v\n", err)
+		os.Exit(1)
+	}
+	defer cache.Close()
+
+	dispatcher := tierrouter.NewDispatcher(collyBin, rodBin, playwrightCmd, cache)
+
+	for _, u := range urls {
+		outcome, err := dispatcher.Crawl(u)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", u, err)
+			continue
+		}
+		fmt.Println(string(outcome.Output))
+	}
+
+	if showTierStats {
+		printTierStats(dispatcher.Stats.Snapshot())
+	}
+}
+
+func printTierStats(counts map[tierrouter.Tier]int64) {
+	encoded, _ := json.MarshalIndent(counts, "", "  ")
+	fmt.Fprintf(os.Stderr, "Tier stats:\n%s\n", string(encoded))
+}
+
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+func printUsage() {
+	fmt.Println(`tiercrawl - adaptive Colly/Rod/Playwright tier router
+
+Usage:
+  tiercrawl --urls=<file> [options]
+
+Options:
+  --urls=<file>            File of newline-separated URLs to crawl (required)
+  --colly-bin=<path>       Path to the colly_crawler binary (default: ./colly_crawler)
+  --rod-bin=<path>         Path to the rod_crawler binary (default: ./rod_crawler)
+  --playwright-cmd=<cmd>   Command for an external Playwright worker, URL appended as its last argument
+  --tier-cache=<path>      Path to the bbolt tier-decision cache (default: tiers.db)
+  --tier-stats             Print a per-tier URL count to stderr when done
+  --help                   Show this help message
+
+Example:
+  tiercrawl --urls=urls.txt --playwright-cmd="python3 playwright_worker.py" --tier-stats`)
+}
+
+func getArgValue(arg string) string {
+	for _, a := range os.Args {
+		if strings.HasPrefix(a, arg+"=") {
+			return strings.TrimPrefix(a, arg+"=")
+		}
+	}
+	return ""
+}
+
+func hasArg(arg string) bool {
+	for _, a := range os.Args {
+		if a == arg {
+			return true
+		}
+	}
+	return false
+}
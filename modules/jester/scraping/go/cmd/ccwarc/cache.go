@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// cacheStats feeds the hit/miss ratio into the progress reporter and the
+// Prometheus endpoint.
+var cacheStats struct {
+	Hits   int64
+	Misses int64
+}
+
+// ccCache is the process-wide CDX cache, nil unless --cache-dir was set.
+var ccCache *CDXCache
+
+// CDXCache is a content-addressed, gzipped-NDJSON cache of CDX query results,
+// keyed by (archive, matchType, domain). It exists because CC Index lookups
+// dominate wall time for small domains and index.commoncrawl.org rate-limits
+// aggressively, so repeated runs over the same domain set shouldn't have to
+// re-query it.
+type CDXCache struct {
+	dir       string
+	ttl       time.Duration
+	cacheOnly bool
+}
+
+// setupCache reads the shared --cache-dir/--cache-ttl/--cache-only flags and,
+// if a cache dir was given, installs it as the process-wide ccCache.
+func setupCache() {
+	dir := getArgValue("--cache-dir")
+	if dir == "" {
+		return
+	}
+
+	ttlSecs := getIntArg("--cache-ttl", 86400)
+	cacheOnly := hasArg("--cache-only")
+
+	cache, err := newCDXCache(dir, time.Duration(ttlSecs)*time.Second, cacheOnly)
+	if err != nil {
+		log.Fatalf("Failed to open CDX cache at %s: %v", dir, err)
+	}
+	ccCache = cache
+}
+
+func newCDXCache(dir string, ttl time.Duration, cacheOnly bool) (*CDXCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &CDXCache{dir: dir, ttl: ttl, cacheOnly: cacheOnly}, nil
+}
+
+func (c *CDXCache) keyPath(archive, matchType, domain string) string {
+	h := sha1.Sum([]byte(archive + "|" + matchType + "|" + domain))
+	return filepath.Join(c.dir, hex.EncodeToString(h[:])+".ndjson.gz")
+}
+
+// Get returns the cached records for (archive, matchType, domain), or false
+// if there's no entry, it's expired, or it's unreadable.
+func (c *CDXCache) Get(archive, matchType, domain string) ([]CCIndexRecord, bool) {
+	path := c.keyPath(archive, matchType, domain)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		atomic.AddInt64(&cacheStats.Misses, 1)
+		return nil, false
+	}
+	if c.ttl > 0 && time.Since(info.ModTime()) > c.ttl {
+		atomic.AddInt64(&cacheStats.Misses, 1)
+		return nil, false
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		atomic.AddInt64(&cacheStats.Misses, 1)
+		return nil, false
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		atomic.AddInt64(&cacheStats.Misses, 1)
+		return nil, false
+	}
+	defer gz.Close()
+
+	var records []CCIndexRecord
+	scanner := bufio.NewScanner(gz)
+	buf := make([]byte, 1024*1024)
+	scanner.Buffer(buf, 1024*1024)
+	for scanner.Scan() {
+		var rec CCIndexRecord
+		if json.Unmarshal(scanner.Bytes(), &rec) == nil {
+			records = append(records, rec)
+		}
+	}
+
+	atomic.AddInt64(&cacheStats.Hits, 1)
+	return records, true
+}
+
+// Put writes records for (archive, matchType, domain) atomically (write to a
+// temp file, then rename) so a crash mid-write can't leave a truncated entry
+// that Get would misread as a legitimate empty result.
+func (c *CDXCache) Put(archive, matchType, domain string, records []CCIndexRecord) error {
+	path := c.keyPath(archive, matchType, domain)
+	tmp := path + ".tmp"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(f)
+	for _, rec := range records {
+		b, err := json.Marshal(rec)
+		if err != nil {
+			gz.Close()
+			f.Close()
+			os.Remove(tmp)
+			return err
+		}
+		gz.Write(b)
+		gz.Write([]byte("\n"))
+	}
+
+	if err := gz.Close(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
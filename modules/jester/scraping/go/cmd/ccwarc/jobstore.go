@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// JobState tracks where a domain is in the index -> fetch -> write pipeline
+// so a `batch --resume=jobs.db` run can pick up where a prior one left off.
+type JobState string
+
+const (
+	StatePending JobState = "pending"
+	StateIndexed JobState = "indexed"
+	StateFetched JobState = "fetched"
+	StateWritten JobState = "written"
+	StateFailed  JobState = "failed"
+)
+
+var jobsBucket = []byte("jobs")
+
+// JobRecord is what's persisted per domain in the job store.
+type JobRecord struct {
+	Domain     string    `json:"domain"`
+	State      JobState  `json:"state"`
+	Attempts   int       `json:"attempts"`
+	FailReason string    `json:"fail_reason,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// JobStore is a thin bbolt-backed KV store keyed by domain, giving `batch`
+// Ctrl-C/resume semantics without pulling in a full job queue system.
+type JobStore struct {
+	db *bbolt.DB
+}
+
+func OpenJobStore(path string) (*JobStore, error) {
+	db, err := bbolt.Open(path, 0644, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("could not open job store %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(jobsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &JobStore{db: db}, nil
+}
+
+func (s *JobStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *JobStore) Get(domain string) (JobRecord, bool) {
+	var rec JobRecord
+	found := false
+	s.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(jobsBucket).Get([]byte(domain))
+		if v == nil {
+			return nil
+		}
+		if err := json.Unmarshal(v, &rec); err == nil {
+			found = true
+		}
+		return nil
+	})
+	return rec, found
+}
+
+// SetState records a domain's new state, bumping Attempts whenever the
+// transition is into StateFailed so callers can enforce a retry ceiling.
+func (s *JobStore) SetState(domain string, state JobState, reason string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(jobsBucket)
+
+		rec := JobRecord{Domain: domain}
+		if v := bucket.Get([]byte(domain)); v != nil {
+			json.Unmarshal(v, &rec)
+		}
+
+		rec.State = state
+		rec.UpdatedAt = time.Now()
+		if state == StateFailed {
+			rec.Attempts++
+			rec.FailReason = reason
+		} else {
+			rec.FailReason = ""
+		}
+
+		encoded, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(domain), encoded)
+	})
+}
+
+// PendingDomains returns the domains from the given list that still need
+// work: never seen, not yet StateWritten, or StateFailed below maxAttempts.
+func (s *JobStore) PendingDomains(domains []string, maxAttempts int) []string {
+	var pending []string
+	for _, domain := range domains {
+		rec, found := s.Get(domain)
+		if !found {
+			pending = append(pending, domain)
+			continue
+		}
+		if rec.State == StateWritten {
+			continue
+		}
+		if rec.State == StateFailed && maxAttempts > 0 && rec.Attempts >= maxAttempts {
+			continue
+		}
+		pending = append(pending, domain)
+	}
+	return pending
+}
+
+// All returns every job record, sorted by domain, for `ccwarc status`.
+func (s *JobStore) All() ([]JobRecord, error) {
+	var records []JobRecord
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(jobsBucket).ForEach(func(k, v []byte) error {
+			var rec JobRecord
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return nil
+			}
+			records = append(records, rec)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Domain < records[j].Domain })
+	return records, nil
+}
+
+func handleStatusCommand() {
+	dbPath := getArgValue("--db")
+	if dbPath == "" {
+		dbPath = getArgValue("--resume")
+	}
+	if dbPath == "" {
+		log.Fatal("Error: --db parameter is required")
+	}
+
+	store, err := OpenJobStore(dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open job store: %v", err)
+	}
+	defer store.Close()
+
+	records, err := store.All()
+	if err != nil {
+		log.Fatalf("Failed to read job store: %v", err)
+	}
+
+	counts := map[JobState]int{}
+	for _, rec := range records {
+		counts[rec.State]++
+		fmt.Printf("%-40s %-10s attempts=%d  %s\n", rec.Domain, rec.State, rec.Attempts, rec.FailReason)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nTotal: %d | pending=%d indexed=%d fetched=%d written=%d failed=%d\n",
+		len(records), counts[StatePending], counts[StateIndexed], counts[StateFetched], counts[StateWritten], counts[StateFailed])
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"compress/gzip"
+	"crypto/sha1"
+	"encoding/base32"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,11 +17,13 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/nlnwa/gowarc"
 )
 
 const (
-	CC_INDEX_BASE = "https://index.commoncrawl.org"
-	CC_DATA_BASE  = "https://data.commoncrawl.org"
+	CC_INDEX_BASE   = "https://index.commoncrawl.org"
+	CC_DATA_BASE    = "https://data.commoncrawl.org"
 	DEFAULT_ARCHIVE = "CC-MAIN-2024-51"
 )
 
@@ -37,15 +42,19 @@ type CCIndexRecord struct {
 
 // ContentResult represents fetched content
 type ContentResult struct {
-	Domain        string `json:"domain"`
-	URL           string `json:"url"`
-	Content       string `json:"content"`
-	ContentLength int    `json:"content_length"`
-	Status        int    `json:"status"`
-	LatencyMs     int64  `json:"latency_ms"`
-	Source        string `json:"source"`
-	WARCPath      string `json:"warc_path,omitempty"`
-	Error         string `json:"error,omitempty"`
+	Domain          string            `json:"domain"`
+	URL             string            `json:"url"`
+	Content         string            `json:"content"`
+	ContentLength   int               `json:"content_length"`
+	Status          int               `json:"status"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	ContentType     string            `json:"content_type,omitempty"`
+	ContentEncoding string            `json:"content_encoding,omitempty"`
+	PayloadDigest   string            `json:"payload_digest,omitempty"`
+	LatencyMs       int64             `json:"latency_ms"`
+	Source          string            `json:"source"`
+	WARCPath        string            `json:"warc_path,omitempty"`
+	Error           string            `json:"error,omitempty"`
 }
 
 // Stats for progress tracking
@@ -58,6 +67,10 @@ type Stats struct {
 
 var stats Stats
 
+// maxBodyBytes caps how many payload bytes are kept in memory per fetched
+// record (0 = unlimited). Set from --max-body-bytes.
+var maxBodyBytes int64
+
 func main() {
 	log.SetOutput(os.Stderr)
 
@@ -75,6 +88,8 @@ func main() {
 		handleIndexCommand()
 	case "batch":
 		handleBatchCommand()
+	case "status":
+		handleStatusCommand()
 	case "--help", "-h":
 		printUsage()
 	default:
@@ -92,6 +107,7 @@ func printUsage() {
 	fmt.Println("  fetch    Fetch content for domains from CC WARC files")
 	fmt.Println("  index    Query CC Index for domain locations (without fetching)")
 	fmt.Println("  batch    Process domains from file with CC Index + WARC fetch")
+	fmt.Println("  status   Inspect a --resume job store left behind by a batch run")
 	fmt.Println()
 	fmt.Println("FETCH USAGE (with pre-computed index records):")
 	fmt.Println("  ./ccwarc fetch --records=index_records.ndjson --threads=50")
@@ -100,7 +116,10 @@ func printUsage() {
 	fmt.Println("  ./ccwarc index --domains=domain1.com,domain2.com --archive=CC-MAIN-2024-51")
 	fmt.Println()
 	fmt.Println("BATCH USAGE (full pipeline: index lookup + WARC fetch):")
-	fmt.Println("  ./ccwarc batch --input=domains.txt --archive=CC-MAIN-2024-51 --threads=50")
+	fmt.Println("  ./ccwarc batch --input=domains.txt --archive=CC-MAIN-2024-51 --threads=50 --resume=jobs.db")
+	fmt.Println()
+	fmt.Println("STATUS USAGE:")
+	fmt.Println("  ./ccwarc status --db=jobs.db")
 	fmt.Println()
 	fmt.Println("OPTIONS:")
 	fmt.Println("  --domains=DOMAINS      Comma-separated domains")
@@ -110,6 +129,20 @@ func printUsage() {
 	fmt.Println("  --threads=NUM          Concurrent fetches (default: 50)")
 	fmt.Println("  --output=FILE          Output file (default: stdout)")
 	fmt.Println("  --timeout=SECS         Request timeout (default: 30)")
+	fmt.Println("  --max-body-bytes=NUM   Truncate payloads mid-stream past this size (default: unlimited)")
+	fmt.Println("  --providers=LIST       Index providers to query: cc,wayback,urlscan,otx (default: cc)")
+	fmt.Println("  --archives=LIST        CC archives to fan the cc provider out across; also 'all' or 'latest-N'")
+	fmt.Println("  --match-type=TYPE      CDX matchType: domain|host|prefix|exact (default: domain)")
+	fmt.Println("  --limit-per-domain=N   Stop paginating a domain once this many records are found")
+	fmt.Println("  --resume=FILE          Job store (bbolt) for batch; skips domains already written, retries failures")
+	fmt.Println("  --max-attempts=N       Give up retrying a failed domain after this many attempts (default: 3)")
+	fmt.Println("  --metrics-addr=HOST:PORT  Serve Prometheus metrics at /metrics during batch runs (default: off)")
+	fmt.Println("  --cache-dir=DIR        Cache CDX query results here as gzipped NDJSON (default: off)")
+	fmt.Println("  --cache-ttl=SECS       CDX cache entry lifetime (default: 86400)")
+	fmt.Println("  --cache-only           Fail closed on a cache miss instead of querying the index API")
+	fmt.Println("  --status=CODE          Only keep records with this HTTP status")
+	fmt.Println("  --mime=TYPE            Only keep records whose mime contains this substring")
+	fmt.Println("  --exclude-ext=LIST     Drop records whose URL ends in one of these extensions")
 }
 
 func handleFetchCommand() {
@@ -121,6 +154,7 @@ func handleFetchCommand() {
 	threads := getIntArg("--threads", 50)
 	outputFile := getArgValue("--output")
 	timeout := getIntArg("--timeout", 30)
+	maxBodyBytes = int64(getIntArg("--max-body-bytes", 0))
 
 	log.Printf("Loading CC Index records from: %s\n", recordsFile)
 
@@ -136,7 +170,9 @@ func handleFetchCommand() {
 	results := fetchWARCContent(records, threads, timeout)
 
 	// Output
-	writeResults(results, outputFile)
+	if err := writeResults(results, outputFile); err != nil {
+		log.Fatalf("Failed to write results: %v", err)
+	}
 
 	log.Printf("Done. Success: %d, Failed: %d\n", stats.Success, stats.Failed)
 }
@@ -144,9 +180,9 @@ func handleFetchCommand() {
 func handleIndexCommand() {
 	domainsStr := getArgValue("--domains")
 	inputFile := getArgValue("--input")
-	archive := getArgValue("--archive")
-	if archive == "" {
-		archive = DEFAULT_ARCHIVE
+	archivesFlag := getArgValue("--archives")
+	if archivesFlag == "" {
+		archivesFlag = getArgValue("--archive")
 	}
 
 	var domains []string
@@ -165,11 +201,14 @@ func handleIndexCommand() {
 
 	threads := getIntArg("--threads", 20)
 	outputFile := getArgValue("--output")
+	setupCache()
+	providerNames := getArgValue("--providers")
+	providers := resolveProviders(providerNames, archivesFlag)
+	filter := parseProviderFilter()
 
-	log.Printf("Querying CC Index for %d domains (archive: %s)\n", len(domains), archive)
+	log.Printf("Querying providers %v for %d domains (archives: %s)\n", providerNames, len(domains), archivesFlag)
 
-	// Query CC Index
-	records := queryIndexBatch(domains, archive, threads)
+	records := fetchFromProviders(domains, providers, filter, threads)
 
 	log.Printf("Found %d index records\n", len(records))
 
@@ -199,14 +238,25 @@ func handleBatchCommand() {
 		log.Fatal("Error: --input parameter is required")
 	}
 
-	archive := getArgValue("--archive")
-	if archive == "" {
-		archive = DEFAULT_ARCHIVE
+	archivesFlag := getArgValue("--archives")
+	if archivesFlag == "" {
+		archivesFlag = getArgValue("--archive")
 	}
 
 	threads := getIntArg("--threads", 50)
 	outputFile := getArgValue("--output")
 	timeout := getIntArg("--timeout", 30)
+	maxBodyBytes = int64(getIntArg("--max-body-bytes", 0))
+	resumeDB := getArgValue("--resume")
+	maxAttempts := getIntArg("--max-attempts", 3)
+
+	setupCache()
+
+	if addr := getArgValue("--metrics-addr"); addr != "" {
+		startMetricsServer(addr)
+	}
+	stopReporter := startStatsReporter(10 * time.Second)
+	defer stopReporter()
 
 	// Load domains
 	domains, err := loadDomainsFromFile(inputFile)
@@ -214,23 +264,75 @@ func handleBatchCommand() {
 		log.Fatalf("Failed to load domains: %v", err)
 	}
 
+	var store *JobStore
+	if resumeDB != "" {
+		store, err = OpenJobStore(resumeDB)
+		if err != nil {
+			log.Fatalf("Failed to open job store: %v", err)
+		}
+		defer store.Close()
+
+		pending := store.PendingDomains(domains, maxAttempts)
+		log.Printf("Resuming from %s: %d/%d domains still need work\n", resumeDB, len(pending), len(domains))
+		domains = pending
+	}
+
 	stats.Total = int64(len(domains))
-	log.Printf("Processing %d domains (archive: %s, threads: %d)\n", len(domains), archive, threads)
+	log.Printf("Processing %d domains (archives: %s, threads: %d)\n", len(domains), archivesFlag, threads)
 
-	// Phase 1: Query CC Index
-	log.Println("Phase 1: Querying CC Index...")
+	providerNames := getArgValue("--providers")
+	providers := resolveProviders(providerNames, archivesFlag)
+	filter := parseProviderFilter()
+
+	// Phase 1: Query providers (CC by default, plus any of wayback/urlscan/otx)
+	log.Println("Phase 1: Querying providers...")
 	indexStart := time.Now()
-	records := queryIndexBatch(domains, archive, threads/2) // Use half threads for index
+	records := fetchFromProviders(domains, providers, filter, threads/2) // Use half threads for index
 	log.Printf("  Found %d index records in %v\n", len(records), time.Since(indexStart))
 
+	if store != nil {
+		indexed := make(map[string]bool, len(records))
+		for _, rec := range records {
+			if !indexed[rec.URLKey] {
+				store.SetState(rec.URLKey, StateIndexed, "")
+				indexed[rec.URLKey] = true
+			}
+		}
+		for _, domain := range domains {
+			if !indexed[domain] {
+				store.SetState(domain, StateFailed, "no index records found")
+			}
+		}
+	}
+
 	// Phase 2: Fetch WARC content
 	log.Println("Phase 2: Fetching WARC content...")
 	fetchStart := time.Now()
 	results := fetchWARCContent(records, threads, timeout)
 	log.Printf("  Fetched %d results in %v\n", len(results), time.Since(fetchStart))
 
+	if store != nil {
+		for _, result := range results {
+			if result.Error != "" {
+				store.SetState(result.Domain, StateFailed, result.Error)
+			} else {
+				store.SetState(result.Domain, StateFetched, "")
+			}
+		}
+	}
+
 	// Output
-	writeResults(results, outputFile)
+	if err := writeResults(results, outputFile); err != nil {
+		log.Fatalf("Failed to write results: %v", err)
+	}
+
+	if store != nil {
+		for _, result := range results {
+			if result.Error == "" {
+				store.SetState(result.Domain, StateWritten, "")
+			}
+		}
+	}
 
 	log.Printf("Done. Total: %d, CC Index hits: %d, Content fetched: %d, Failed: %d\n",
 		stats.Total, stats.IndexHits, stats.Success, stats.Failed)
@@ -280,64 +382,105 @@ func loadIndexRecords(path string) ([]CCIndexRecord, error) {
 	return records, scanner.Err()
 }
 
-func queryIndexBatch(domains []string, archive string, threads int) []CCIndexRecord {
-	var results []CCIndexRecord
-	var mutex sync.Mutex
-	var wg sync.WaitGroup
-	guard := make(chan struct{}, threads)
+// queryIndex fetches every CDX page for domain against a single archive,
+// with matchType=domain and no record limit. queryIndexPaged takes the
+// matchType (one of domain|host|prefix|exact, mirroring the CDX server's
+// &matchType= param) and stops early once limitPerDomain records have been
+// collected when limitPerDomain > 0.
+func queryIndex(client *http.Client, domain, archive string) []CCIndexRecord {
+	return queryIndexPaged(client, domain, archive, "domain", 0)
+}
 
-	client := &http.Client{Timeout: 15 * time.Second}
+func queryIndexPaged(client *http.Client, domain, archive, matchType string, limitPerDomain int) []CCIndexRecord {
+	if matchType == "" {
+		matchType = "domain"
+	}
 
-	for _, domain := range domains {
-		wg.Add(1)
-		guard <- struct{}{}
+	if ccCache != nil {
+		if records, ok := ccCache.Get(archive, matchType, domain); ok {
+			return records
+		}
+		if ccCache.cacheOnly {
+			log.Fatalf("Error: --cache-only set and no cache entry for %s (archive=%s, matchType=%s)", domain, archive, matchType)
+		}
+	}
 
-		go func(d string) {
-			defer wg.Done()
-			defer func() { <-guard }()
+	records := queryIndexPagedLive(client, domain, archive, matchType, limitPerDomain)
+
+	if ccCache != nil && !ccCache.cacheOnly {
+		if err := ccCache.Put(archive, matchType, domain, records); err != nil {
+			log.Printf("⚠️  Failed to write CDX cache entry for %s: %v\n", domain, err)
+		}
+	}
 
-			records := queryIndex(client, d, archive)
-			if len(records) > 0 {
-				atomic.AddInt64(&stats.IndexHits, 1)
-				mutex.Lock()
-				// Take best record (first one, usually most recent)
-				results = append(results, records[0])
-				mutex.Unlock()
+	return records
+}
+
+func queryIndexPagedLive(client *http.Client, domain, archive, matchType string, limitPerDomain int) []CCIndexRecord {
+	numPages := fetchNumPages(client, domain, archive, matchType)
+
+	var records []CCIndexRecord
+	for page := 0; page < numPages; page++ {
+		url := fmt.Sprintf("%s/%s-index?url=%s&matchType=%s&output=json&fl=url,timestamp,digest,length,offset,filename,status,mime&page=%d",
+			CC_INDEX_BASE, archive, domain, matchType, page)
+
+		resp, err := client.Get(url)
+		if err != nil {
+			break
+		}
+
+		if resp.StatusCode != 200 {
+			resp.Body.Close()
+			break
+		}
+
+		scanner := bufio.NewScanner(resp.Body)
+		buf := make([]byte, 1024*1024)
+		scanner.Buffer(buf, 1024*1024)
+		for scanner.Scan() {
+			var rec CCIndexRecord
+			if err := json.Unmarshal(scanner.Bytes(), &rec); err == nil {
+				rec.URLKey = domain
+				records = append(records, rec)
+				if limitPerDomain > 0 && len(records) >= limitPerDomain {
+					resp.Body.Close()
+					return records
+				}
 			}
-		}(domain)
+		}
+		resp.Body.Close()
 	}
 
-	wg.Wait()
-	return results
+	return records
 }
 
-func queryIndex(client *http.Client, domain, archive string) []CCIndexRecord {
-	// Query for domain/* to get all pages
-	url := fmt.Sprintf("%s/%s-index?url=%s/*&output=json&fl=url,timestamp,digest,length,offset,filename,status,mime&limit=1",
-		CC_INDEX_BASE, archive, domain)
+// fetchNumPages asks the CDX server how many pages a query spans via
+// showNumPages=true. Falls back to a single page on any error so callers
+// degrade gracefully instead of failing outright.
+func fetchNumPages(client *http.Client, domain, archive, matchType string) int {
+	url := fmt.Sprintf("%s/%s-index?url=%s&matchType=%s&showNumPages=true",
+		CC_INDEX_BASE, archive, domain, matchType)
 
 	resp, err := client.Get(url)
 	if err != nil {
-		return nil
+		return 1
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return nil
+		return 1
 	}
 
-	var records []CCIndexRecord
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		var rec CCIndexRecord
-		if err := json.Unmarshal(scanner.Bytes(), &rec); err == nil {
-			// Add domain for convenience
-			rec.URLKey = domain
-			records = append(records, rec)
-		}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 1
 	}
 
-	return records
+	n, err := strconv.Atoi(strings.TrimSpace(string(body)))
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
 }
 
 func fetchWARCContent(records []CCIndexRecord, threads, timeout int) []ContentResult {
@@ -375,8 +518,6 @@ func fetchWARCContent(records []CCIndexRecord, threads, timeout int) []ContentRe
 func fetchWARC(client *http.Client, rec CCIndexRecord) ContentResult {
 	start := time.Now()
 
-	// Parse offset and length
-	offset, _ := strconv.ParseInt(rec.Offset, 10, 64)
 	length, _ := strconv.ParseInt(rec.Length, 10, 64)
 
 	if rec.Filename == "" || length == 0 {
@@ -388,113 +529,153 @@ func fetchWARC(client *http.Client, rec CCIndexRecord) ContentResult {
 		}
 	}
 
-	// Range request to CC
+	// Range request to CC, with one retry if the payload fails digest validation
+	// (a corrupted/truncated range read should not be silently returned as content).
+	var result ContentResult
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		result, err = fetchWARCRange(client, rec, start)
+		if err == nil {
+			return result
+		}
+		log.Printf("⚠️  WARC fetch attempt %d failed for %s: %v\n", attempt+1, rec.URL, err)
+	}
+
+	return ContentResult{
+		Domain:    rec.URLKey,
+		URL:       rec.URL,
+		Error:     err.Error(),
+		Source:    "cc_failed",
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+}
+
+func fetchWARCRange(client *http.Client, rec CCIndexRecord, start time.Time) (ContentResult, error) {
+	offset, _ := strconv.ParseInt(rec.Offset, 10, 64)
+	length, _ := strconv.ParseInt(rec.Length, 10, 64)
+
 	warcURL := fmt.Sprintf("%s/%s", CC_DATA_BASE, rec.Filename)
-	req, _ := http.NewRequest("GET", warcURL, nil)
+	req, err := http.NewRequest("GET", warcURL, nil)
+	if err != nil {
+		return ContentResult{}, err
+	}
 	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return ContentResult{
-			Domain:    rec.URLKey,
-			URL:       rec.URL,
-			Error:     err.Error(),
-			Source:    "cc_failed",
-			LatencyMs: time.Since(start).Milliseconds(),
-		}
+		return ContentResult{}, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 && resp.StatusCode != 206 {
-		return ContentResult{
-			Domain:    rec.URLKey,
-			URL:       rec.URL,
-			Status:    resp.StatusCode,
-			Error:     fmt.Sprintf("http_%d", resp.StatusCode),
-			Source:    "cc_failed",
-			LatencyMs: time.Since(start).Milliseconds(),
-		}
+		return ContentResult{}, fmt.Errorf("http_%d", resp.StatusCode)
 	}
 
-	// Read compressed data
-	compressed, err := io.ReadAll(resp.Body)
+	// Stream straight from the response body through gzip into the WARC/HTTP
+	// parsers instead of buffering the whole range in memory first; this keeps
+	// per-worker memory bounded regardless of record size.
+	result, err := parseWARCRecord(resp.Body, maxBodyBytes)
 	if err != nil {
-		return ContentResult{
-			Domain:    rec.URLKey,
-			URL:       rec.URL,
-			Error:     "read_error",
-			Source:    "cc_failed",
-			LatencyMs: time.Since(start).Milliseconds(),
-		}
+		return ContentResult{}, err
 	}
 
-	// Decompress
-	content := extractHTMLFromWARC(compressed)
-	if content == "" {
-		return ContentResult{
-			Domain:    rec.URLKey,
-			URL:       rec.URL,
-			Error:     "decompress_failed",
-			Source:    "cc_failed",
-			LatencyMs: time.Since(start).Milliseconds(),
-		}
+	if rec.Digest != "" && result.PayloadDigest != "" && !strings.EqualFold(result.PayloadDigest, rec.Digest) {
+		return ContentResult{}, fmt.Errorf("digest_mismatch: got %s want %s", result.PayloadDigest, rec.Digest)
 	}
 
-	status, _ := strconv.Atoi(rec.Status)
+	result.Domain = rec.URLKey
+	result.URL = rec.URL
+	result.LatencyMs = time.Since(start).Milliseconds()
+	result.Source = "cc"
+	result.WARCPath = rec.Filename
 
-	return ContentResult{
-		Domain:        rec.URLKey,
-		URL:           rec.URL,
-		Content:       content,
-		ContentLength: len(content),
-		Status:        status,
-		LatencyMs:     time.Since(start).Milliseconds(),
-		Source:        "cc",
-		WARCPath:      rec.Filename,
-	}
+	metrics.observeFetch(rec.Filename, time.Since(start), result.ContentLength)
+
+	return result, nil
 }
 
-func extractHTMLFromWARC(compressed []byte) string {
-	// Try gzip decompression
-	reader, err := gzip.NewReader(strings.NewReader(string(compressed)))
-	var data []byte
-	if err == nil {
-		data, err = io.ReadAll(reader)
-		reader.Close()
-		if err != nil {
-			// Fall back to raw data
-			data = compressed
-		}
-	} else {
-		// Not gzipped, use raw
-		data = compressed
+// parseWARCRecord streams a single gzip'd WARC record off r (as returned by a CC
+// range request) through gowarc, so we get the real HTTP status code, headers,
+// content-type and a SHA-1/base32 payload digest instead of guessing at
+// `\r\n\r\n` boundaries on a fully materialized buffer. maxBody caps how many
+// payload bytes are kept in memory; the rest of the body is drained and
+// discarded so the digest check below still runs against the full payload.
+func parseWARCRecord(r io.Reader, maxBody int64) (ContentResult, error) {
+	gzReader, err := gzip.NewReader(bufio.NewReader(r))
+	if err != nil {
+		return ContentResult{}, fmt.Errorf("gzip_open_failed: %w", err)
 	}
+	defer gzReader.Close()
 
-	text := string(data)
+	unmarshaler := gowarc.NewUnmarshaler(gowarc.WithNoValidation())
+	rec, _, _, err := unmarshaler.Unmarshal(bufio.NewReader(gzReader))
+	if err != nil {
+		return ContentResult{}, fmt.Errorf("warc_parse_failed: %w", err)
+	}
+	defer rec.Close()
+
+	block, ok := rec.Block().(gowarc.HttpResponseBlock)
+	if !ok {
+		return ContentResult{}, fmt.Errorf("not_an_http_response_record")
+	}
 
-	// WARC format: WARC headers, blank line, HTTP headers, blank line, body
-	// Find the double CRLF separating sections
-	parts := strings.SplitN(text, "\r\n\r\n", 3)
+	httpResp, err := block.Response()
+	if err != nil {
+		return ContentResult{}, fmt.Errorf("http_response_parse_failed: %w", err)
+	}
+	defer httpResp.Body.Close()
 
-	var body string
-	if len(parts) >= 3 {
-		// parts[0] = WARC headers, parts[1] = HTTP headers, parts[2] = body
-		body = parts[2]
-	} else if len(parts) == 2 {
-		body = parts[1]
+	hasher := sha1.New()
+	var payload bytes.Buffer
+	truncated := false
+	if maxBody > 0 {
+		n, err := io.CopyN(io.MultiWriter(&payload, hasher), httpResp.Body, maxBody)
+		if err != nil && err != io.EOF {
+			return ContentResult{}, fmt.Errorf("payload_read_failed: %w", err)
+		}
+		truncated = n == maxBody
+		if truncated {
+			// Keep hashing the remainder (against the declared digest) without
+			// holding it in memory.
+			if _, err := io.Copy(hasher, httpResp.Body); err != nil {
+				return ContentResult{}, fmt.Errorf("payload_drain_failed: %w", err)
+			}
+		}
 	} else {
-		body = text
+		if _, err := io.Copy(io.MultiWriter(&payload, hasher), httpResp.Body); err != nil {
+			return ContentResult{}, fmt.Errorf("payload_read_failed: %w", err)
+		}
 	}
 
-	// Basic validation - should look like HTML
-	if strings.Contains(body, "<") && strings.Contains(body, ">") {
-		return body
+	headers := make(map[string]string, len(httpResp.Header))
+	for k := range httpResp.Header {
+		headers[k] = httpResp.Header.Get(k)
 	}
 
-	return ""
+	// Hash of the payload actually read, not the record's own
+	// WARC-Payload-Digest header, so the caller's comparison with the index
+	// digest catches a corrupted or truncated range read.
+	digest := base32.StdEncoding.EncodeToString(hasher.Sum(nil))
+
+	content := payload.String()
+	if truncated {
+		content += "...[truncated]"
+	}
+
+	return ContentResult{
+		Content:         content,
+		ContentLength:   payload.Len(),
+		Status:          httpResp.StatusCode,
+		Headers:         headers,
+		ContentType:     httpResp.Header.Get("Content-Type"),
+		ContentEncoding: httpResp.Header.Get("Content-Encoding"),
+		PayloadDigest:   digest,
+	}, nil
 }
 
-func writeResults(results []ContentResult, outputFile string) {
+// writeResults streams results out as NDJSON. Any marshal or write failure
+// aborts the run with an error rather than silently dropping a record.
+func writeResults(results []ContentResult, outputFile string) error {
 	var out *os.File
 	if outputFile == "" || outputFile == "-" {
 		out = os.Stdout
@@ -502,16 +683,24 @@ func writeResults(results []ContentResult, outputFile string) {
 		var err error
 		out, err = os.Create(outputFile)
 		if err != nil {
-			log.Fatalf("Failed to create output file: %v", err)
+			return fmt.Errorf("failed to create output file: %w", err)
 		}
 		defer out.Close()
 	}
 
 	for _, result := range results {
-		jsonBytes, _ := json.Marshal(result)
-		out.Write(jsonBytes)
-		out.WriteString("\n")
+		jsonBytes, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to marshal result for %s: %w", result.Domain, err)
+		}
+		if _, err := out.Write(jsonBytes); err != nil {
+			return fmt.Errorf("failed to write result for %s: %w", result.Domain, err)
+		}
+		if _, err := out.WriteString("\n"); err != nil {
+			return fmt.Errorf("failed to write result for %s: %w", result.Domain, err)
+		}
 	}
+	return nil
 }
 
 // Helper functions for argument parsing
@@ -532,3 +721,13 @@ func getIntArg(arg string, defaultVal int) int {
 	}
 	return defaultVal
 }
+
+// hasArg reports whether a bare boolean flag (no "=value") was passed.
+func hasArg(arg string) bool {
+	for _, a := range os.Args {
+		if a == arg {
+			return true
+		}
+	}
+	return false
+}
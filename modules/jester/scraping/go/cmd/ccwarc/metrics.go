@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// metricsState accumulates the counters and latency samples behind the 10s
+// progress ticker and the optional --metrics-addr Prometheus endpoint. Stats
+// stays the simple end-of-run summary; this is everything needed to report
+// on a run while it's still going.
+type metricsState struct {
+	bytesTotal int64 // atomic
+
+	mu         sync.Mutex
+	latencies  []float64 // fetch latency seconds, most recent maxLatencySamples
+	perArchive map[string]int64
+}
+
+const maxLatencySamples = 10000
+
+var metrics = &metricsState{perArchive: make(map[string]int64)}
+
+var archivePattern = regexp.MustCompile(`CC-MAIN-\d{4}-\d{2}`)
+
+// archiveFromFilename pulls the archive ID (e.g. "CC-MAIN-2024-51") out of a
+// WARC filename like "crawl-data/CC-MAIN-2024-51/segments/.../xyz.warc.gz",
+// so per-archive metrics don't need the archive threaded through separately.
+func archiveFromFilename(filename string) string {
+	return archivePattern.FindString(filename)
+}
+
+func (m *metricsState) observeFetch(filename string, latency time.Duration, bytes int) {
+	atomic.AddInt64(&m.bytesTotal, int64(bytes))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.latencies) >= maxLatencySamples {
+		m.latencies = m.latencies[1:]
+	}
+	m.latencies = append(m.latencies, latency.Seconds())
+	if archive := archiveFromFilename(filename); archive != "" {
+		m.perArchive[archive]++
+	}
+}
+
+// percentiles returns P50/P95 fetch latency in seconds over the recent
+// sample window.
+func (m *metricsState) percentiles() (p50, p95 float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.latencies) == 0 {
+		return 0, 0
+	}
+	sorted := append([]float64(nil), m.latencies...)
+	sort.Float64s(sorted)
+	p50 = percentileOf(sorted, 0.50)
+	p95 = percentileOf(sorted, 0.95)
+	return
+}
+
+// percentileOf indexes into an already-sorted slice, clamped to the last
+// element so p=0.95 on a handful of samples doesn't go out of range.
+func percentileOf(sorted []float64, p float64) float64 {
+	idx := int(p * float64(len(sorted)))
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// startStatsReporter logs throughput, cache-hit ratio and ETA every interval
+// until the returned stop func is called, mirroring the humanize-based stats
+// loops used elsewhere in this repo for long-running jobs.
+func startStatsReporter(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		lastSuccess := int64(0)
+		lastBytes := int64(0)
+		lastTick := time.Now()
+
+		for {
+			select {
+			case <-done:
+				ticker.Stop()
+				return
+			case now := <-ticker.C:
+				elapsed := now.Sub(lastTick).Seconds()
+				if elapsed <= 0 {
+					elapsed = interval.Seconds()
+				}
+
+				success := atomic.LoadInt64(&stats.Success)
+				failed := atomic.LoadInt64(&stats.Failed)
+				bytesTotal := atomic.LoadInt64(&metrics.bytesTotal)
+				total := atomic.LoadInt64(&stats.Total)
+				cacheHits := atomic.LoadInt64(&cacheStats.Hits)
+				cacheMisses := atomic.LoadInt64(&cacheStats.Misses)
+
+				recordsPerSec := float64(success-lastSuccess) / elapsed
+				mbPerSec := float64(bytesTotal-lastBytes) / elapsed / (1024 * 1024)
+				p50, p95 := metrics.percentiles()
+
+				done := success + failed
+				var eta time.Duration
+				if recordsPerSec > 0 && total > done {
+					eta = time.Duration(float64(total-done)/recordsPerSec) * time.Second
+				}
+
+				cacheRatio := 0.0
+				if cacheHits+cacheMisses > 0 {
+					cacheRatio = float64(cacheHits) / float64(cacheHits+cacheMisses) * 100
+				}
+
+				log.Printf("📊 %d/%d done | %.1f rec/s, %.2f MB/s | cache hit %.0f%% | p50=%.2fs p95=%.2fs | ETA %s\n",
+					done, total, recordsPerSec, mbPerSec, cacheRatio, p50, p95, eta.Round(time.Second))
+
+				lastSuccess = success
+				lastBytes = bytesTotal
+				lastTick = now
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// startMetricsServer exposes the same counters as a minimal hand-rolled
+// Prometheus text-format endpoint, so a batch run can be scraped instead of
+// (or alongside) tailing NDJSON output. Kept dependency-free rather than
+// pulling in client_golang for four counters and a histogram.
+func startMetricsServer(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", handleMetrics)
+
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("⚠️  metrics server stopped: %v\n", err)
+		}
+	}()
+	log.Printf("🚀 Metrics endpoint listening on %s/metrics\n", addr)
+}
+
+var latencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# HELP ccwarc_records_fetched_total Records fetched, by outcome.\n")
+	fmt.Fprintf(&b, "# TYPE ccwarc_records_fetched_total counter\n")
+	fmt.Fprintf(&b, "ccwarc_records_fetched_total{outcome=\"success\"} %d\n", atomic.LoadInt64(&stats.Success))
+	fmt.Fprintf(&b, "ccwarc_records_fetched_total{outcome=\"failed\"} %d\n", atomic.LoadInt64(&stats.Failed))
+
+	fmt.Fprintf(&b, "# HELP ccwarc_bytes_total Payload bytes fetched from WARC records.\n")
+	fmt.Fprintf(&b, "# TYPE ccwarc_bytes_total counter\n")
+	fmt.Fprintf(&b, "ccwarc_bytes_total %d\n", atomic.LoadInt64(&metrics.bytesTotal))
+
+	fmt.Fprintf(&b, "# HELP ccwarc_cache_lookups_total CDX cache lookups, by result.\n")
+	fmt.Fprintf(&b, "# TYPE ccwarc_cache_lookups_total counter\n")
+	fmt.Fprintf(&b, "ccwarc_cache_lookups_total{result=\"hit\"} %d\n", atomic.LoadInt64(&cacheStats.Hits))
+	fmt.Fprintf(&b, "ccwarc_cache_lookups_total{result=\"miss\"} %d\n", atomic.LoadInt64(&cacheStats.Misses))
+
+	metrics.mu.Lock()
+	samples := append([]float64(nil), metrics.latencies...)
+	perArchive := make(map[string]int64, len(metrics.perArchive))
+	for k, v := range metrics.perArchive {
+		perArchive[k] = v
+	}
+	metrics.mu.Unlock()
+
+	fmt.Fprintf(&b, "# HELP ccwarc_fetch_latency_seconds WARC fetch latency.\n")
+	fmt.Fprintf(&b, "# TYPE ccwarc_fetch_latency_seconds histogram\n")
+	for _, bucket := range latencyBuckets {
+		count := 0
+		for _, s := range samples {
+			if s <= bucket {
+				count++
+			}
+		}
+		fmt.Fprintf(&b, "ccwarc_fetch_latency_seconds_bucket{le=\"%g\"} %d\n", bucket, count)
+	}
+	fmt.Fprintf(&b, "ccwarc_fetch_latency_seconds_bucket{le=\"+Inf\"} %d\n", len(samples))
+	sum := 0.0
+	for _, s := range samples {
+		sum += s
+	}
+	fmt.Fprintf(&b, "ccwarc_fetch_latency_seconds_sum %g\n", sum)
+	fmt.Fprintf(&b, "ccwarc_fetch_latency_seconds_count %d\n", len(samples))
+
+	fmt.Fprintf(&b, "# HELP ccwarc_archive_records_total Records fetched per archive.\n")
+	fmt.Fprintf(&b, "# TYPE ccwarc_archive_records_total counter\n")
+	for archive, count := range perArchive {
+		fmt.Fprintf(&b, "ccwarc_archive_records_total{archive=\"%s\"} %d\n", archive, count)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write([]byte(b.String()))
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Provider is a pluggable source of historical URL/snapshot records for a
+// domain. CommonCrawl is the original (and still default) provider; Wayback,
+// URLScan and OTX let callers fan a domain out across several archives in a
+// single `index`/`batch` run.
+type Provider interface {
+	Name() string
+	Fetch(ctx context.Context, domain string) ([]CCIndexRecord, error)
+}
+
+// ProviderFilter bounds what a provider returns, applied uniformly across
+// providers so --providers=cc,wayback,otx behaves the same regardless of
+// which one produced a given record.
+type ProviderFilter struct {
+	Status      string
+	Mime        string
+	ExcludeExts []string
+}
+
+func (f ProviderFilter) keep(rec CCIndexRecord) bool {
+	if f.Status != "" && rec.Status != "" && rec.Status != f.Status {
+		return false
+	}
+	if f.Mime != "" && rec.Mime != "" && !strings.Contains(rec.Mime, f.Mime) {
+		return false
+	}
+	for _, ext := range f.ExcludeExts {
+		if ext != "" && strings.HasSuffix(strings.ToLower(rec.URL), strings.ToLower(ext)) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveProviders turns a --providers=cc,wayback,... flag value into
+// Provider implementations, defaulting to CommonCrawl alone when unset.
+// archivesFlag supports a single archive name, a comma-separated list, the
+// literal "all", or "latest-N", resolved against collinfo.json.
+func resolveProviders(names, archivesFlag string) []Provider {
+	if names == "" {
+		names = "cc"
+	}
+
+	client := &http.Client{Timeout: 15 * time.Second}
+
+	var providers []Provider
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "cc", "commoncrawl", "common-crawl":
+			providers = append(providers, &CommonCrawlProvider{
+				client:         client,
+				archives:       resolveArchives(client, archivesFlag),
+				matchType:      getArgValue("--match-type"),
+				limitPerDomain: getIntArg("--limit-per-domain", 0),
+			})
+		case "wayback":
+			providers = append(providers, &WaybackProvider{client: client})
+		case "urlscan":
+			providers = append(providers, &URLScanProvider{client: client})
+		case "otx":
+			providers = append(providers, &OTXProvider{client: client})
+		default:
+			log.Printf("⚠️  Unknown provider %q, skipping\n", name)
+		}
+	}
+	return providers
+}
+
+// resolveArchives expands the --archives flag ("CC-MAIN-2024-51,...", "all",
+// or "latest-N") into a concrete list of archive IDs, querying collinfo.json
+// for "all"/"latest-N".
+func resolveArchives(client *http.Client, archivesFlag string) []string {
+	if archivesFlag == "" {
+		return []string{DEFAULT_ARCHIVE}
+	}
+	if !strings.EqualFold(archivesFlag, "all") && !strings.HasPrefix(strings.ToLower(archivesFlag), "latest-") {
+		return strings.Split(archivesFlag, ",")
+	}
+
+	all, err := fetchCollinfo(client)
+	if err != nil || len(all) == 0 {
+		log.Printf("⚠️  Could not resolve --archives=%s from collinfo.json: %v; falling back to %s\n", archivesFlag, err, DEFAULT_ARCHIVE)
+		return []string{DEFAULT_ARCHIVE}
+	}
+
+	if strings.EqualFold(archivesFlag, "all") {
+		return all
+	}
+
+	n := 1
+	fmt.Sscanf(strings.ToLower(archivesFlag), "latest-%d", &n)
+	if n > len(all) {
+		n = len(all)
+	}
+	return all[:n]
+}
+
+// fetchCollinfo returns every known CC archive ID, newest first, as published
+// at https://index.commoncrawl.org/collinfo.json.
+func fetchCollinfo(client *http.Client) ([]string, error) {
+	resp, err := client.Get("https://index.commoncrawl.org/collinfo.json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("collinfo: http %d", resp.StatusCode)
+	}
+
+	var entries []struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("collinfo: decode failed: %w", err)
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+	return ids, nil
+}
+
+// CommonCrawlProvider wraps the CC Index query, fanning a domain out across
+// every archive it's configured with and paginating each one fully.
+type CommonCrawlProvider struct {
+	client         *http.Client
+	archives       []string
+	matchType      string
+	limitPerDomain int
+}
+
+func (p *CommonCrawlProvider) Name() string { return "cc" }
+
+func (p *CommonCrawlProvider) Fetch(ctx context.Context, domain string) ([]CCIndexRecord, error) {
+	var records []CCIndexRecord
+	for _, archive := range p.archives {
+		records = append(records, queryIndexPaged(p.client, domain, archive, p.matchType, p.limitPerDomain)...)
+	}
+	return records, nil
+}
+
+// WaybackProvider queries the Internet Archive's CDX API.
+type WaybackProvider struct {
+	client *http.Client
+}
+
+func (p *WaybackProvider) Name() string { return "wayback" }
+
+func (p *WaybackProvider) Fetch(ctx context.Context, domain string) ([]CCIndexRecord, error) {
+	url := fmt.Sprintf("http://web.archive.org/cdx/search/cdx?url=%s/*&output=json", domain)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("wayback: http %d", resp.StatusCode)
+	}
+
+	// The CDX JSON API returns a JSON array-of-arrays, header row first:
+	// [["urlkey","timestamp","original","mimetype","statuscode","digest","length"], ...]
+	var rows [][]string
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("wayback: decode failed: %w", err)
+	}
+
+	var records []CCIndexRecord
+	for i, row := range rows {
+		if i == 0 || len(row) < 7 {
+			continue // header row
+		}
+		records = append(records, CCIndexRecord{
+			URLKey:    domain,
+			Timestamp: row[1],
+			URL:       row[2],
+			Mime:      row[3],
+			Status:    row[4],
+			Digest:    row[5],
+			Length:    row[6],
+		})
+	}
+	return records, nil
+}
+
+// URLScanProvider queries urlscan.io's search API for prior scans of a domain.
+type URLScanProvider struct {
+	client *http.Client
+}
+
+func (p *URLScanProvider) Name() string { return "urlscan" }
+
+func (p *URLScanProvider) Fetch(ctx context.Context, domain string) ([]CCIndexRecord, error) {
+	url := fmt.Sprintf("https://urlscan.io/api/v1/search/?q=domain:%s", domain)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("urlscan: http %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Results []struct {
+			Page struct {
+				URL    string `json:"url"`
+				Domain string `json:"domain"`
+			} `json:"page"`
+			Task struct {
+				Time string `json:"time"`
+			} `json:"task"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("urlscan: decode failed: %w", err)
+	}
+
+	var records []CCIndexRecord
+	for _, r := range body.Results {
+		records = append(records, CCIndexRecord{
+			URLKey:    domain,
+			URL:       r.Page.URL,
+			Timestamp: r.Task.Time,
+		})
+	}
+	return records, nil
+}
+
+// OTXProvider queries AlienVault OTX's passive-DNS/url-list endpoint.
+type OTXProvider struct {
+	client *http.Client
+}
+
+func (p *OTXProvider) Name() string { return "otx" }
+
+func (p *OTXProvider) Fetch(ctx context.Context, domain string) ([]CCIndexRecord, error) {
+	url := fmt.Sprintf("https://otx.alienvault.com/api/v1/indicators/domain/%s/url_list?limit=100", domain)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("otx: http %d", resp.StatusCode)
+	}
+
+	var body struct {
+		URLList []struct {
+			URL    string `json:"url"`
+			Date   string `json:"date"`
+			Digest string `json:"sha1,omitempty"`
+		} `json:"url_list"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("otx: decode failed: %w", err)
+	}
+
+	var records []CCIndexRecord
+	for _, u := range body.URLList {
+		records = append(records, CCIndexRecord{
+			URLKey:    domain,
+			URL:       u.URL,
+			Timestamp: u.Date,
+			Digest:    u.Digest,
+		})
+	}
+	return records, nil
+}
+
+// dedupeRecords drops records that share a URL+digest pair, keeping the
+// first occurrence so results fetched from multiple providers for the same
+// page don't get fetched twice in the WARC phase.
+func dedupeRecords(records []CCIndexRecord) []CCIndexRecord {
+	seen := make(map[string]bool, len(records))
+	deduped := make([]CCIndexRecord, 0, len(records))
+	for _, rec := range records {
+		key := rec.URL + "|" + rec.Digest
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		deduped = append(deduped, rec)
+	}
+	return deduped
+}
+
+// parseProviderFilter reads the shared --status/--mime/--exclude-ext flags
+// used to bound what every registered provider returns.
+func parseProviderFilter() ProviderFilter {
+	filter := ProviderFilter{
+		Status: getArgValue("--status"),
+		Mime:   getArgValue("--mime"),
+	}
+	if exts := getArgValue("--exclude-ext"); exts != "" {
+		filter.ExcludeExts = strings.Split(exts, ",")
+	}
+	return filter
+}
+
+// fetchFromProviders queries every provider for every domain concurrently
+// (bounded by threads) and returns the filtered, deduplicated union.
+func fetchFromProviders(domains []string, providers []Provider, filter ProviderFilter, threads int) []CCIndexRecord {
+	if threads < 1 {
+		// A caller passing threads/2 (e.g. --threads=1) would otherwise spawn
+		// zero workers: wg.Wait() returns immediately, resultsCh closes with
+		// nothing read from it, and the producer goroutine below leaks
+		// forever blocked on its unbuffered send to jobs.
+		threads = 1
+	}
+
+	type job struct {
+		domain   string
+		provider Provider
+	}
+
+	jobs := make(chan job)
+	resultsCh := make(chan []CCIndexRecord, len(domains)*len(providers))
+
+	go func() {
+		defer close(jobs)
+		for _, domain := range domains {
+			for _, p := range providers {
+				jobs <- job{domain: domain, provider: p}
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				recs, err := j.provider.Fetch(context.Background(), j.domain)
+				if err != nil {
+					log.Printf("⚠️  %s: %s: %v\n", j.provider.Name(), j.domain, err)
+					continue
+				}
+				var kept []CCIndexRecord
+				for _, rec := range recs {
+					if filter.keep(rec) {
+						kept = append(kept, rec)
+					}
+				}
+				if len(kept) > 0 {
+					atomic.AddInt64(&stats.IndexHits, 1)
+				}
+				resultsCh <- kept
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(resultsCh)
+	}()
+
+	var all []CCIndexRecord
+	for recs := range resultsCh {
+		all = append(all, recs...)
+	}
+
+	return dedupeRecords(all)
+}
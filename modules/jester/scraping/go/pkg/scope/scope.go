@@ -0,0 +1,243 @@
+// Package scope decides which links a crawler should follow and how far,
+// shared by rod_crawler (JS rendering) and colly_crawler (static HTML) so
+// both paths apply the same seed-prefix/domain/regex rules instead of each
+// re-deriving "is this link in scope" on its own.
+package scope
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// LinkTag marks whether a discovered resource is a Primary navigational
+// link (subject to full depth/scope rules) or a Related resource (an
+// <img>/<link>/<script>/CSS url() reference the current page needs to
+// render correctly) that is collected one hop off the page, regardless of
+// scope. The crawlers list Related resources rather than fetch them; only
+// linked .css files are fetched, to scan them for url(...) references.
+type LinkTag string
+
+const (
+	LinkPrimary LinkTag = "primary"
+	LinkRelated LinkTag = "related"
+)
+
+// ResourceRecord is a single related-resource reference discovered on a
+// page: an image, stylesheet, script, or CSS url(...) target.
+type ResourceRecord struct {
+	URL string  `json:"url"`
+	Tag LinkTag `json:"tag"`
+}
+
+// Policy configures what counts as "in scope" for primary link following.
+// A zero-value Policy is wide open: every link not caught by Exclude is in
+// scope.
+type Policy struct {
+	// SeedPrefixes restricts primary links to URLs starting with one of
+	// these strings (e.g. the seed URL's path, to stay under a section of
+	// a site).
+	SeedPrefixes []string
+
+	// SameRegistrableDomain restricts primary links to the same
+	// registrable domain (eTLD+1, via publicsuffix) as the seed they were
+	// found on.
+	SameRegistrableDomain bool
+
+	// Include, if non-empty, requires a primary link to match at least one
+	// of these regexes.
+	Include []*regexp.Regexp
+
+	// Exclude rejects a primary link matching any of these regexes, even
+	// if Include or the other rules would otherwise have allowed it.
+	Exclude []*regexp.Regexp
+}
+
+// NewPolicy compiles includePatterns/excludePatterns into a Policy. A
+// malformed pattern is dropped rather than failing the whole policy,
+// matching the regex-filter convention cclinks' --exclude-from-file uses.
+func NewPolicy(seedPrefixes []string, sameRegistrableDomain bool, includePatterns, excludePatterns []string) Policy {
+	p := Policy{SeedPrefixes: seedPrefixes, SameRegistrableDomain: sameRegistrableDomain}
+	for _, pat := range includePatterns {
+		if re, err := regexp.Compile(pat); err == nil {
+			p.Include = append(p.Include, re)
+		}
+	}
+	for _, pat := range excludePatterns {
+		if re, err := regexp.Compile(pat); err == nil {
+			p.Exclude = append(p.Exclude, re)
+		}
+	}
+	return p
+}
+
+// InScope reports whether link (discovered on a page whose URL was seed)
+// should be followed as a Primary link under p.
+func (p Policy) InScope(seed, link *url.URL) bool {
+	linkStr := link.String()
+
+	for _, re := range p.Exclude {
+		if re.MatchString(linkStr) {
+			return false
+		}
+	}
+
+	if len(p.Include) > 0 {
+		matched := false
+		for _, re := range p.Include {
+			if re.MatchString(linkStr) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(p.SeedPrefixes) > 0 {
+		matched := false
+		for _, prefix := range p.SeedPrefixes {
+			if strings.HasPrefix(linkStr, prefix) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if p.SameRegistrableDomain && seed != nil && !SameRegistrableDomain(seed.Host, link.Host) {
+		return false
+	}
+
+	return true
+}
+
+// SameRegistrableDomain reports whether hostA and hostB share the same
+// registrable domain (eTLD+1) per the Public Suffix List, so
+// "www.example.com" and "shop.example.com" match but "example.com" and
+// "example.co.uk" don't. Falls back to an exact host comparison if either
+// host can't be parsed against the suffix list (e.g. bare IPs), the same
+// fallback hostcaps.go uses for its registrable-domain caps.
+func SameRegistrableDomain(hostA, hostB string) bool {
+	a, errA := publicsuffix.EffectiveTLDPlusOne(stripPort(hostA))
+	if errA != nil {
+		a = stripPort(hostA)
+	}
+	b, errB := publicsuffix.EffectiveTLDPlusOne(stripPort(hostB))
+	if errB != nil {
+		b = stripPort(hostB)
+	}
+	return a == b
+}
+
+func stripPort(host string) string {
+	if i := strings.LastIndexByte(host, ':'); i != -1 {
+		return host[:i]
+	}
+	return host
+}
+
+var (
+	imgSrcRegex    = regexp.MustCompile(`(?is)<img\s+[^>]*src=["']([^"']+)["']`)
+	linkCSSRegex   = regexp.MustCompile(`(?is)<link\s+[^>]*rel=["']stylesheet["'][^>]*href=["']([^"']+)["']|<link\s+[^>]*href=["']([^"']+)["'][^>]*rel=["']stylesheet["']`)
+	scriptSrcRegex = regexp.MustCompile(`(?is)<script\s+[^>]*src=["']([^"']+)["']`)
+	sourceSrcRegex = regexp.MustCompile(`(?is)<source\s+[^>]*src=["']([^"']+)["']`)
+	srcsetRegex    = regexp.MustCompile(`(?is)srcset=["']([^"']+)["']`)
+	styleTagRegex  = regexp.MustCompile(`(?is)<style[^>]*>(.*?)</style>`)
+	cssURLRegex    = regexp.MustCompile(`(?i)url\(\s*["']?([^"')]+)["']?\s*\)`)
+)
+
+// ParseRelated scans html for <img src>, <link rel=stylesheet href>,
+// <script src>, <source src> (picture/video/audio), and srcset (on <img>
+// or <source>) references and returns them as Related ResourceRecords
+// with relative URLs resolved against base. It also returns the text of
+// every inline <style> block found, so a caller can run ParseCSSURLs over
+// those too without a second HTML scan.
+func ParseRelated(html string, base *url.URL) (related []ResourceRecord, inlineCSS []string) {
+	for _, m := range imgSrcRegex.FindAllStringSubmatch(html, -1) {
+		if r, ok := resolveRelated(m[1], base); ok {
+			related = append(related, r)
+		}
+	}
+	for _, m := range linkCSSRegex.FindAllStringSubmatch(html, -1) {
+		href := m[1]
+		if href == "" {
+			href = m[2]
+		}
+		if r, ok := resolveRelated(href, base); ok {
+			related = append(related, r)
+		}
+	}
+	for _, m := range scriptSrcRegex.FindAllStringSubmatch(html, -1) {
+		if r, ok := resolveRelated(m[1], base); ok {
+			related = append(related, r)
+		}
+	}
+	for _, m := range sourceSrcRegex.FindAllStringSubmatch(html, -1) {
+		if r, ok := resolveRelated(m[1], base); ok {
+			related = append(related, r)
+		}
+	}
+	for _, m := range srcsetRegex.FindAllStringSubmatch(html, -1) {
+		for _, ref := range parseSrcset(m[1]) {
+			if r, ok := resolveRelated(ref, base); ok {
+				related = append(related, r)
+			}
+		}
+	}
+	for _, m := range styleTagRegex.FindAllStringSubmatch(html, -1) {
+		inlineCSS = append(inlineCSS, m[1])
+	}
+	return related, inlineCSS
+}
+
+// parseSrcset splits a srcset attribute value ("a.jpg 1x, b-2x.jpg 2x")
+// into its candidate URLs, dropping each candidate's width/pixel-density
+// descriptor.
+func parseSrcset(value string) []string {
+	var urls []string
+	for _, candidate := range strings.Split(value, ",") {
+		fields := strings.Fields(strings.TrimSpace(candidate))
+		if len(fields) > 0 {
+			urls = append(urls, fields[0])
+		}
+	}
+	return urls
+}
+
+// ParseCSSURLs extracts url(...) references from css (an inline <style>
+// block or a fetched .css file's body) and resolves them against base.
+func ParseCSSURLs(css string, base *url.URL) []ResourceRecord {
+	var related []ResourceRecord
+	for _, m := range cssURLRegex.FindAllStringSubmatch(css, -1) {
+		if r, ok := resolveRelated(m[1], base); ok {
+			related = append(related, r)
+		}
+	}
+	return related
+}
+
+func resolveRelated(ref string, base *url.URL) (ResourceRecord, bool) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "data:") {
+		return ResourceRecord{}, false
+	}
+
+	parsed, err := url.Parse(ref)
+	if err != nil {
+		return ResourceRecord{}, false
+	}
+	if base != nil && !parsed.IsAbs() {
+		parsed = base.ResolveReference(parsed)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return ResourceRecord{}, false
+	}
+
+	return ResourceRecord{URL: parsed.String(), Tag: LinkRelated}, true
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// SeedSource is a pluggable source of historical URLs for a domain, queried
+// by the `discover` command to build a seeds.json the `crawl` command can
+// then consume. Modeled on ccwarc's Provider interface, trimmed down to
+// just the URL (discover doesn't need digest/status/mime, only enough to
+// seed a crawl).
+type SeedSource interface {
+	Name() string
+	Fetch(ctx context.Context, domain string, includeSubs bool) ([]string, error)
+}
+
+// CommonCrawlSeedSource queries a single CC archive's CDX index, the same
+// API ccwarc's CommonCrawlProvider uses, paginated via showNumPages.
+type CommonCrawlSeedSource struct {
+	client  *http.Client
+	archive string
+}
+
+func (s *CommonCrawlSeedSource) Name() string { return "cc" }
+
+func (s *CommonCrawlSeedSource) Fetch(ctx context.Context, domain string, includeSubs bool) ([]string, error) {
+	matchType := "exact"
+	if includeSubs {
+		matchType = "domain"
+	}
+
+	numPages, err := fetchCDXNumPages(ctx, s.client,
+		fmt.Sprintf("https://index.commoncrawl.org/%s-index", s.archive), domain, matchType)
+	if err != nil {
+		return nil, fmt.Errorf("cc: %w", err)
+	}
+
+	var urls []string
+	for page := 0; page < numPages; page++ {
+		reqURL := fmt.Sprintf("https://index.commoncrawl.org/%s-index?url=%s&matchType=%s&output=json&fl=url&page=%d",
+			s.archive, domain, matchType, page)
+		lines, err := getLines(ctx, s.client, reqURL)
+		if err != nil {
+			break // a failed page shouldn't discard pages already fetched
+		}
+		for _, line := range lines {
+			var rec struct {
+				URL string `json:"url"`
+			}
+			if json.Unmarshal([]byte(line), &rec) == nil && rec.URL != "" {
+				urls = append(urls, rec.URL)
+			}
+		}
+	}
+	return urls, nil
+}
+
+// fetchCDXNumPages asks a CDX server how many pages a query spans via
+// showNumPages=true, falling back to a single page on any error so a
+// source degrades gracefully rather than failing outright.
+func fetchCDXNumPages(ctx context.Context, client *http.Client, indexURL, domain, matchType string) (int, error) {
+	reqURL := fmt.Sprintf("%s?url=%s&matchType=%s&showNumPages=true", indexURL, domain, matchType)
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return 1, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 1, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return 1, nil
+	}
+
+	var body struct {
+		Pages int `json:"pages"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Pages == 0 {
+		return 1, nil
+	}
+	return body.Pages, nil
+}
+
+// WaybackSeedSource queries the Internet Archive's CDX API, matching
+// ccwarc's WaybackProvider.
+type WaybackSeedSource struct {
+	client *http.Client
+}
+
+func (s *WaybackSeedSource) Name() string { return "wayback" }
+
+func (s *WaybackSeedSource) Fetch(ctx context.Context, domain string, includeSubs bool) ([]string, error) {
+	matchType := "exact"
+	target := domain
+	if includeSubs {
+		matchType = "domain"
+	}
+
+	reqURL := fmt.Sprintf("http://web.archive.org/cdx/search/cdx?url=%s&matchType=%s&output=json&fl=original&collapse=urlkey",
+		target, matchType)
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("wayback: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("wayback: http %d", resp.StatusCode)
+	}
+
+	// output=json for the Wayback CDX API is a JSON array-of-arrays, header
+	// row first: [["original"], [...], ...]
+	var rows [][]string
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("wayback: decode failed: %w", err)
+	}
+
+	var urls []string
+	for i, row := range rows {
+		if i == 0 || len(row) < 1 {
+			continue
+		}
+		urls = append(urls, row[0])
+	}
+	return urls, nil
+}
+
+// VirusTotalSeedSource queries VirusTotal's domain relationships endpoint
+// for URLs VT has observed for a domain. Requires an API key (--vt-api-key
+// or the VT_API_KEY environment variable); resolveSeedSources skips this
+// source entirely, with a warning, if neither is set.
+type VirusTotalSeedSource struct {
+	client *http.Client
+	apiKey string
+}
+
+func (s *VirusTotalSeedSource) Name() string { return "vt" }
+
+func (s *VirusTotalSeedSource) Fetch(ctx context.Context, domain string, includeSubs bool) ([]string, error) {
+	var urls []string
+	next := fmt.Sprintf("https://www.virustotal.com/api/v3/domains/%s/urls?limit=40", domain)
+
+	for next != "" {
+		req, err := http.NewRequestWithContext(ctx, "GET", next, nil)
+		if err != nil {
+			return urls, err
+		}
+		req.Header.Set("x-apikey", s.apiKey)
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return urls, fmt.Errorf("vt: %w", err)
+		}
+
+		if resp.StatusCode == 429 {
+			resp.Body.Close()
+			time.Sleep(15 * time.Second)
+			continue
+		}
+		if resp.StatusCode != 200 {
+			resp.Body.Close()
+			return urls, fmt.Errorf("vt: http %d", resp.StatusCode)
+		}
+
+		var body struct {
+			Data []struct {
+				Attributes struct {
+					URL string `json:"url"`
+				} `json:"attributes"`
+			} `json:"data"`
+			Links struct {
+				Next string `json:"next"`
+			} `json:"links"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if err != nil {
+			return urls, fmt.Errorf("vt: decode failed: %w", err)
+		}
+
+		for _, d := range body.Data {
+			if d.Attributes.URL != "" {
+				urls = append(urls, d.Attributes.URL)
+			}
+		}
+		next = body.Links.Next
+	}
+
+	return urls, nil
+}
+
+// getLines issues a GET and returns its body split into lines, the shape
+// the CDX JSON-lines APIs (one JSON object per line, not a JSON array)
+// return.
+func getLines(ctx context.Context, client *http.Client, reqURL string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("http %d", resp.StatusCode)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	buf := make([]byte, 1024*1024)
+	scanner.Buffer(buf, 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// resolveSeedSources turns a --sources=cc,wayback,vt flag value into
+// SeedSource implementations, defaulting to CommonCrawl alone when unset.
+func resolveSeedSources(names string, client *http.Client, ccArchive, vtAPIKey string) []SeedSource {
+	if names == "" {
+		names = "cc"
+	}
+
+	var sources []SeedSource
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "cc", "commoncrawl", "common-crawl":
+			sources = append(sources, &CommonCrawlSeedSource{client: client, archive: ccArchive})
+		case "wayback":
+			sources = append(sources, &WaybackSeedSource{client: client})
+		case "vt", "virustotal":
+			if vtAPIKey == "" {
+				vtAPIKey = os.Getenv("VT_API_KEY")
+			}
+			if vtAPIKey == "" {
+				fmt.Fprintln(os.Stderr, "Warning: --sources includes vt but no --vt-api-key/VT_API_KEY set; skipping")
+				continue
+			}
+			sources = append(sources, &VirusTotalSeedSource{client: client, apiKey: vtAPIKey})
+		default:
+			fmt.Fprintf(os.Stderr, "Warning: unknown seed source %q, skipping\n", name)
+		}
+	}
+	return sources
+}
+
+// dedupeBloom is a minimal fixed-size bit-array bloom filter using double
+// hashing, the same fnv-based construction and hash count as cclinks'
+// sniperstate.go bloom filter but with a quarter of its bits (see
+// discoverBloomBits), and in-memory only: discover is a
+// one-shot run producing a seeds.json, not a resumable crawl, so there's
+// nothing to persist it to.
+type dedupeBloom struct {
+	bits []byte
+	m    uint64
+	k    int
+}
+
+func newDedupeBloom(m uint64, k int) *dedupeBloom {
+	return &dedupeBloom{bits: make([]byte, (m+7)/8), m: m, k: k}
+}
+
+func (b *dedupeBloom) positions(key string) []uint64 {
+	h1 := fnv.New64a()
+	h1.Write([]byte(key))
+	sum1 := h1.Sum64()
+
+	h2 := fnv.New32a()
+	h2.Write([]byte(key))
+	sum2 := uint64(h2.Sum32())
+
+	positions := make([]uint64, b.k)
+	for i := 0; i < b.k; i++ {
+		positions[i] = (sum1 + uint64(i)*sum2) % b.m
+	}
+	return positions
+}
+
+// testAndAdd reports whether key was already (probably) present, setting
+// its bits as a side effect either way.
+func (b *dedupeBloom) testAndAdd(key string) bool {
+	present := true
+	for _, pos := range b.positions(key) {
+		byteIdx, bitIdx := pos/8, pos%8
+		if b.bits[byteIdx]&(1<<bitIdx) == 0 {
+			present = false
+		}
+		b.bits[byteIdx] |= 1 << bitIdx
+	}
+	return present
+}
+
+const (
+	discoverBloomBits   = 1 << 22 // 512KB; seed discovery runs are one domain at a time, far fewer URLs than cclinks' WAT-wide dedup
+	discoverBloomHashes = 4
+)
+
+// runDiscover queries every configured SeedSource for domain and writes the
+// deduplicated union to outputFile in the same JSON-array-of-strings format
+// the `crawl` command's --urls expects.
+func runDiscover(domain string, sources []SeedSource, includeSubs bool, outputFile string) {
+	if len(sources) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no valid --sources resolved")
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	bloom := newDedupeBloom(discoverBloomBits, discoverBloomHashes)
+
+	var seeds []string
+	for _, src := range sources {
+		urls, err := src.Fetch(ctx, domain, includeSubs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", src.Name(), err)
+			continue
+		}
+		for _, u := range urls {
+			if bloom.testAndAdd(u) {
+				continue
+			}
+			seeds = append(seeds, u)
+		}
+		fmt.Fprintf(os.Stderr, "%s: %d URLs (%d unique so far)\n", src.Name(), len(urls), len(seeds))
+	}
+
+	encoded, err := json.MarshalIndent(seeds, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding seeds: %v\n", err)
+		os.Exit(1)
+	}
+
+	var output io.Writer = os.Stdout
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		output = f
+	}
+
+	fmt.Fprintln(output, string(encoded))
+	fmt.Fprintf(os.Stderr, "Discovered %d unique URLs for %s\n", len(seeds), domain)
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.etcd.io/bbolt"
+	"golang.org/x/net/publicsuffix"
+
+	"jester/scraping/go/pkg/scope"
+)
+
+// Item is a single unit of frontier work: a URL discovered at some crawl
+// depth, tagged Primary (a navigational link, bounded by --max-depth) or
+// Related (a page dependency; the frontier never enforces depth on these,
+// though rod_crawler doesn't currently route Related resources through it
+// at all — see collectRelated).
+type Item struct {
+	URL   string        `json:"url"`
+	Depth int           `json:"depth"`
+	Tag   scope.LinkTag `json:"tag"`
+}
+
+// Frontier is the pluggable interface runCrawl drives instead of a
+// preloaded `chan string`, so the to-visit set doesn't have to fit in RAM
+// and so links extractLinks discovers have somewhere to go for recursive
+// crawling. Done must be called exactly once per successful Dequeue, once
+// that item's fetch has finished, to release its host's politeness slot.
+type Frontier interface {
+	Enqueue(item Item) error
+	Dequeue() (Item, bool)
+	Done(url string)
+	Len() int
+	Close() error
+}
+
+var (
+	frontierPendingBucket = []byte("frontier_pending")
+	frontierSeenBucket    = []byte("frontier_seen")
+)
+
+// dequeueScanLimit bounds how many pending items BoltFrontier.Dequeue will
+// look past to find one whose host currently clears the politeness gate,
+// so one slow-walked host can't force a full queue scan on every call.
+const dequeueScanLimit = 256
+
+// BoltFrontier is a bbolt-backed Frontier: pending work lives in a bucket
+// keyed by an ever-increasing sequence number (so a cursor walks it in
+// FIFO order without ever loading the whole queue into memory), and a
+// second bucket records every URL ever enqueued so recursive link
+// discovery doesn't queue the same page twice. maxDepth/maxPages are
+// enforced here, against a single shared counter, so concurrent workers
+// calling Enqueue/Dequeue agree on when the crawl's limits are hit.
+type BoltFrontier struct {
+	db   *bbolt.DB
+	gate *hostGate
+
+	mu       sync.Mutex
+	maxDepth int
+	maxPages int
+	dequeued int
+}
+
+// OpenBoltFrontier opens (creating if needed) the bbolt db at path.
+// maxDepth < 0 means unlimited depth; maxDepth == 0 means "seeds only", the
+// crawl's pre-frontier default. maxPages <= 0 means unlimited pages.
+func OpenBoltFrontier(path string, maxDepth, maxPages int, gate *hostGate) (*BoltFrontier, error) {
+	db, err := bbolt.Open(path, 0644, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("could not open frontier db %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(frontierPendingBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(frontierSeenBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltFrontier{db: db, gate: gate, maxDepth: maxDepth, maxPages: maxPages}, nil
+}
+
+func (f *BoltFrontier) Close() error {
+	return f.db.Close()
+}
+
+func seqKey(seq uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, seq)
+	return b
+}
+
+// Enqueue adds item unless it's already been seen (by URL, across the
+// whole crawl) or, for Primary items, its depth is past maxDepth.
+func (f *BoltFrontier) Enqueue(item Item) error {
+	if item.Tag == scope.LinkPrimary && f.maxDepth >= 0 && item.Depth > f.maxDepth {
+		return nil
+	}
+
+	return f.db.Update(func(tx *bbolt.Tx) error {
+		seen := tx.Bucket(frontierSeenBucket)
+		if seen.Get([]byte(item.URL)) != nil {
+			return nil
+		}
+		if err := seen.Put([]byte(item.URL), []byte{1}); err != nil {
+			return err
+		}
+
+		pending := tx.Bucket(frontierPendingBucket)
+		seq, err := pending.NextSequence()
+		if err != nil {
+			return err
+		}
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		return pending.Put(seqKey(seq), encoded)
+	})
+}
+
+// Dequeue pops the oldest Item whose host currently clears the politeness
+// gate (reserving that host's slot as a side effect — callers must call
+// Done once the fetch finishes), scanning at most dequeueScanLimit
+// candidates so one gated host doesn't force a full queue scan. It returns
+// false once --max-pages has been reached or nothing in the scan window is
+// ready yet; callers should treat the latter as "try again shortly", not
+// "frontier is empty" (use Len for that).
+func (f *BoltFrontier) Dequeue() (Item, bool) {
+	f.mu.Lock()
+	if f.maxPages > 0 && f.dequeued >= f.maxPages {
+		f.mu.Unlock()
+		return Item{}, false
+	}
+	f.mu.Unlock()
+
+	var found Item
+	var foundKey []byte
+
+	err := f.db.Update(func(tx *bbolt.Tx) error {
+		pending := tx.Bucket(frontierPendingBucket)
+		c := pending.Cursor()
+
+		scanned := 0
+		for k, v := c.First(); k != nil && scanned < dequeueScanLimit; k, v = c.Next() {
+			scanned++
+			var item Item
+			if err := json.Unmarshal(v, &item); err != nil {
+				continue
+			}
+			if !f.gate.Allow(item.URL) {
+				continue
+			}
+			found = item
+			foundKey = append([]byte(nil), k...)
+			break
+		}
+		if foundKey == nil {
+			return nil
+		}
+		return pending.Delete(foundKey)
+	})
+	if err != nil || foundKey == nil {
+		return Item{}, false
+	}
+
+	f.mu.Lock()
+	f.dequeued++
+	f.mu.Unlock()
+
+	return found, true
+}
+
+func (f *BoltFrontier) Done(rawURL string) {
+	f.gate.Release(rawURL)
+}
+
+func (f *BoltFrontier) Len() int {
+	var n int
+	f.db.View(func(tx *bbolt.Tx) error {
+		n = tx.Bucket(frontierPendingBucket).Stats().KeyN
+		return nil
+	})
+	return n
+}
+
+// hostGate enforces --host-delay-ms and --host-concurrency politeness
+// limits, keyed by registrable domain (via publicsuffix) so www/blog
+// subdomains of the same site share one budget.
+type hostGate struct {
+	minDelay    time.Duration
+	maxInFlight int
+
+	mu       sync.Mutex
+	lastSeen map[string]time.Time
+	inFlight map[string]int
+}
+
+func newHostGate(minDelay time.Duration, maxInFlight int) *hostGate {
+	return &hostGate{
+		minDelay:    minDelay,
+		maxInFlight: maxInFlight,
+		lastSeen:    make(map[string]time.Time),
+		inFlight:    make(map[string]int),
+	}
+}
+
+// Allow reports whether rawURL's host may be dequeued right now, reserving
+// an in-flight slot for it as a side effect if so; callers must call
+// Release once the fetch finishes.
+func (g *hostGate) Allow(rawURL string) bool {
+	reg := registrableDomain(hostOf(rawURL))
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.maxInFlight > 0 && g.inFlight[reg] >= g.maxInFlight {
+		return false
+	}
+	if g.minDelay > 0 {
+		if last, ok := g.lastSeen[reg]; ok && time.Since(last) < g.minDelay {
+			return false
+		}
+	}
+
+	g.inFlight[reg]++
+	g.lastSeen[reg] = time.Now()
+	return true
+}
+
+func (g *hostGate) Release(rawURL string) {
+	reg := registrableDomain(hostOf(rawURL))
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.inFlight[reg] > 0 {
+		g.inFlight[reg]--
+	}
+}
+
+func registrableDomain(host string) string {
+	reg, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return reg
+}
@@ -13,6 +13,10 @@
 //
 // Usage:
 //   rod_crawler crawl --urls=urls.json --output=results.ndjson --concurrent=50
+//   rod_crawler crawl --urls=urls.json --warc=out.warc.gz --resume=state.db
+//   rod_crawler crawl --urls=seeds.json --max-depth=2 --max-pages=5000 --host-concurrency=2
+//   rod_crawler crawl --urls=urls.json --block=image,media,font --proxy=127.0.0.1:8080 --insecure-tls
+//   rod_crawler discover --domain=example.com --sources=cc,wayback,vt --include-subs --output=seeds.json
 //   rod_crawler test https://example.com
 
 package main
@@ -23,6 +27,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"os"
 	"regexp"
@@ -34,21 +39,34 @@ import (
 	"github.com/go-rod/rod"
 	"github.com/go-rod/rod/lib/launcher"
 	"github.com/go-rod/rod/lib/proto"
+
+	"jester/scraping/go/pkg/scope"
 )
 
 // CrawlResult matches the format from colly_crawler for compatibility
 type CrawlResult struct {
-	URL           string          `json:"url"`
-	StatusCode    int             `json:"status_code"`
-	ContentType   string          `json:"content_type"`
-	Title         string          `json:"title"`
-	Content       string          `json:"content"`
-	HTML          string          `json:"html,omitempty"`
-	Outlinks      []OutlinkRecord `json:"outlinks"`
-	InternalLinks []string        `json:"internal_links"`
-	NeedsJS       bool            `json:"needs_js"` // Always true for Rod results
-	Error         string          `json:"error,omitempty"`
-	LatencyMs     int64           `json:"latency_ms"`
+	URL           string                 `json:"url"`
+	FinalURL      string                 `json:"final_url,omitempty"` // post-redirect URL, if different from URL
+	StatusCode    int                    `json:"status_code"`
+	ContentType   string                 `json:"content_type"`
+	Title         string                 `json:"title"`
+	Content       string                 `json:"content"`
+	HTML          string                 `json:"html,omitempty"`
+	Outlinks      []OutlinkRecord        `json:"outlinks"`
+	InternalLinks []string               `json:"internal_links"`
+	Related       []scope.ResourceRecord `json:"related,omitempty"`
+	Subresources  []SubresourceStat      `json:"subresources,omitempty"`
+	NeedsJS       bool                   `json:"needs_js"` // Always true for Rod results
+	Error         string                 `json:"error,omitempty"`
+	LatencyMs     int64                  `json:"latency_ms"`
+
+	// rawHTML and respHeaders carry the page body and the headers captured
+	// off the NetworkResponseReceived CDP event through to the result
+	// consumer loop in runCrawl, which is the only place that talks to
+	// WarcWriter/CrawlState. They're unexported so they never leak into the
+	// NDJSON output.
+	rawHTML     string
+	respHeaders http.Header
 }
 
 type OutlinkRecord struct {
@@ -60,21 +78,40 @@ type OutlinkRecord struct {
 }
 
 type CrawlStats struct {
-	Total      int64 `json:"total"`
-	Success    int64 `json:"success"`
-	Failed     int64 `json:"failed"`
+	Total       int64 `json:"total"`
+	Success     int64 `json:"success"`
+	Failed      int64 `json:"failed"`
 	TotalTimeMs int64 `json:"total_time_ms"`
 }
 
 var (
 	// Flags
-	urlsFile     string
-	outputFile   string
-	concurrent   int
-	timeout      int
-	includeHTML  bool
-	headless     bool
-	userAgent    string
+	urlsFile        string
+	outputFile      string
+	concurrent      int
+	timeout         int
+	includeHTML     bool
+	headless        bool
+	userAgent       string
+	warcOutput      string
+	resumeDB        string
+	frontierDB      string
+	maxDepth        int
+	maxPages        int
+	hostDelayMs     int
+	hostConcurrency int
+
+	discoverDomain      string
+	discoverSources     string
+	discoverIncludeSubs bool
+	discoverCCArchive   string
+	discoverVTAPIKey    string
+
+	blockTypes  string
+	proxy       string
+	proxyUser   string
+	proxyPass   string
+	insecureTLS bool
 )
 
 func init() {
@@ -85,6 +122,25 @@ func init() {
 	flag.BoolVar(&includeHTML, "include-html", false, "Include raw HTML in output")
 	flag.BoolVar(&headless, "headless", true, "Run browser in headless mode")
 	flag.StringVar(&userAgent, "user-agent", "", "Custom user agent")
+	flag.StringVar(&warcOutput, "warc", "", "Also write request/response records to this WARC file (e.g. out.warc.gz)")
+	flag.StringVar(&resumeDB, "resume", "", "Job store (bbolt) to track crawled URLs; skips URLs already archived, retries failures")
+	flag.StringVar(&frontierDB, "frontier-db", "", "On-disk frontier queue (bbolt); defaults to a temp file removed when the crawl finishes")
+	flag.IntVar(&maxDepth, "max-depth", 0, "Max link-following depth from the seed URLs (0 = seeds only, -1 = unlimited)")
+	flag.IntVar(&maxPages, "max-pages", 0, "Stop once this many pages have been dequeued (0 = unlimited)")
+	flag.IntVar(&hostDelayMs, "host-delay-ms", 0, "Minimum delay between requests to the same registrable domain (0 = no delay)")
+	flag.IntVar(&hostConcurrency, "host-concurrency", 4, "Max concurrent requests to the same registrable domain (0 = unlimited)")
+
+	flag.StringVar(&discoverDomain, "domain", "", "Domain to discover historical URLs for (discover command)")
+	flag.StringVar(&discoverSources, "sources", "cc", "Comma-separated seed sources: cc,wayback,vt")
+	flag.BoolVar(&discoverIncludeSubs, "include-subs", false, "Include subdomains of --domain")
+	flag.StringVar(&discoverCCArchive, "cc-archive", "CC-MAIN-2024-51", "Common Crawl archive ID to query")
+	flag.StringVar(&discoverVTAPIKey, "vt-api-key", "", "VirusTotal API key (falls back to VT_API_KEY env var)")
+
+	flag.StringVar(&blockTypes, "block", "", "Comma-separated resource types to block: image,media,font,stylesheet")
+	flag.StringVar(&proxy, "proxy", "", "Upstream proxy for the browser, e.g. host:port")
+	flag.StringVar(&proxyUser, "proxy-user", "", "Username for an authenticated --proxy")
+	flag.StringVar(&proxyPass, "proxy-pass", "", "Password for an authenticated --proxy")
+	flag.BoolVar(&insecureTLS, "insecure-tls", false, "Ignore TLS certificate errors")
 }
 
 func main() {
@@ -104,6 +160,14 @@ func main() {
 			os.Exit(1)
 		}
 		runCrawl()
+	case "discover":
+		if discoverDomain == "" {
+			fmt.Fprintln(os.Stderr, "Error: --domain required for discover command")
+			os.Exit(1)
+		}
+		client := &http.Client{Timeout: 30 * time.Second}
+		sources := resolveSeedSources(discoverSources, client, discoverCCArchive, discoverVTAPIKey)
+		runDiscover(discoverDomain, sources, discoverIncludeSubs, outputFile)
 	case "test":
 		if len(flag.Args()) < 2 {
 			fmt.Fprintln(os.Stderr, "Error: URL required for test command")
@@ -122,11 +186,13 @@ func printUsage() {
 	fmt.Println()
 	fmt.Println("Usage:")
 	fmt.Println("  rod_crawler crawl --urls=urls.json --output=results.ndjson [options]")
+	fmt.Println("  rod_crawler discover --domain=example.com --sources=cc,wayback,vt --include-subs --output=seeds.json")
 	fmt.Println("  rod_crawler test <url>")
 	fmt.Println()
 	fmt.Println("Commands:")
-	fmt.Println("  crawl    Crawl multiple URLs with JS rendering")
-	fmt.Println("  test     Test crawl a single URL")
+	fmt.Println("  crawl      Crawl multiple URLs with JS rendering")
+	fmt.Println("  discover   Query third-party URL sources for a domain's historical URL surface")
+	fmt.Println("  test       Test crawl a single URL")
 	fmt.Println()
 	fmt.Println("Options:")
 	flag.PrintDefaults()
@@ -153,6 +219,40 @@ func runCrawl() {
 		os.Exit(0)
 	}
 
+	// Open the resume state store, if requested, and drop URLs it already
+	// has marked as fetched so a re-run only archives what's left.
+	var state *CrawlState
+	if resumeDB != "" {
+		var err error
+		state, err = OpenCrawlState(resumeDB)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening resume db: %v\n", err)
+			os.Exit(1)
+		}
+		defer state.Close()
+
+		pending := state.PendingURLs(urls)
+		fmt.Fprintf(os.Stderr, "Resuming from %s: %d/%d URLs still need work\n", resumeDB, len(pending), len(urls))
+		urls = pending
+	}
+
+	if len(urls) == 0 {
+		fmt.Fprintln(os.Stderr, "Nothing left to crawl")
+		os.Exit(0)
+	}
+
+	// Open the WARC output, if requested.
+	var warcWriter *WarcWriter
+	if warcOutput != "" {
+		var err error
+		warcWriter, err = NewWarcWriter(warcOutput)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating WARC output: %v\n", err)
+			os.Exit(1)
+		}
+		defer warcWriter.Close()
+	}
+
 	// Open output file
 	var output io.Writer = os.Stdout
 	if outputFile != "" {
@@ -165,12 +265,14 @@ func runCrawl() {
 		output = f
 	}
 
+	netPolicy := newNetworkPolicy(blockTypes, proxy, proxyUser, proxyPass, insecureTLS)
+
 	// Launch browser
-	l := launcher.New().
+	l := configureLauncherNetwork(launcher.New().
 		Headless(headless).
 		Set("disable-gpu").
 		Set("no-sandbox").
-		Set("disable-dev-shm-usage")
+		Set("disable-dev-shm-usage"), netPolicy)
 
 	browserURL, err := l.Launch()
 	if err != nil {
@@ -188,32 +290,86 @@ func runCrawl() {
 	// Note: User agent is set per-page in crawlURL function via proto.NetworkSetUserAgentOverride
 	_ = userAgent // Use in page context
 
-	// Stats
+	// Stats. Total is no longer known up front now that recursive crawling
+	// can grow the frontier past the seed list, so it's counted as pages
+	// are actually dequeued rather than set to len(urls).
 	var stats CrawlStats
-	stats.Total = int64(len(urls))
 
-	// Create work channel and result channel
-	urlChan := make(chan string, len(urls))
-	resultChan := make(chan CrawlResult, len(urls))
+	// Open the on-disk frontier. Its seen-set means seeds are de-duped
+	// against the resume store's filtering above, and its depth/page caps
+	// are what let discovered links be recursively crawled instead of
+	// thrown away: --max-depth=0 (the default) enqueues the seeds only, so
+	// a plain run behaves exactly as it did with the old urlChan.
+	frontierPath := frontierDB
+	cleanupFrontier := false
+	if frontierPath == "" {
+		f, err := os.CreateTemp("", "rod_crawler-frontier-*.db")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating frontier db: %v\n", err)
+			os.Exit(1)
+		}
+		frontierPath = f.Name()
+		f.Close()
+		cleanupFrontier = true
+	}
+
+	gate := newHostGate(time.Duration(hostDelayMs)*time.Millisecond, hostConcurrency)
+	frontier, err := OpenBoltFrontier(frontierPath, maxDepth, maxPages, gate)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening frontier db: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() {
+		frontier.Close()
+		if cleanupFrontier {
+			os.Remove(frontierPath)
+		}
+	}()
 
-	// Fill URL channel
 	for _, u := range urls {
-		urlChan <- u
+		frontier.Enqueue(Item{URL: u, Depth: 0, Tag: scope.LinkPrimary})
 	}
-	close(urlChan)
 
-	// Spawn workers
+	resultChan := make(chan CrawlResult, concurrent*2)
+
+	// Spawn workers. Each pulls from the frontier rather than ranging over
+	// a pre-filled channel, so links discovered by extractLinks can be fed
+	// back in via Enqueue and picked up by any worker, not just the one
+	// that found them.
 	var wg sync.WaitGroup
 	for i := 0; i < concurrent; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for u := range urlChan {
-				result := crawlURL(browser, u, timeout)
+			for {
+				item, ok := frontier.Dequeue()
+				if !ok {
+					if frontier.Len() == 0 || (maxPages > 0 && atomic.LoadInt64(&stats.Total) >= int64(maxPages)) {
+						return
+					}
+					// Everything currently pending is held back by host
+					// politeness (or the page cap, until the pages already
+					// dequeued finish); give it a moment rather than
+					// busy-spinning on the frontier.
+					time.Sleep(50 * time.Millisecond)
+					continue
+				}
+
+				result := crawlURL(browser, item.URL, timeout, netPolicy)
+				frontier.Done(item.URL)
+
 				if result.Error == "" {
 					atomic.AddInt64(&stats.Success, 1)
+					atomic.AddInt64(&stats.Total, 1)
+					for _, link := range result.Outlinks {
+						frontier.Enqueue(Item{URL: link.URL, Depth: item.Depth + 1, Tag: scope.LinkPrimary})
+					}
+					for _, link := range result.InternalLinks {
+						frontier.Enqueue(Item{URL: link, Depth: item.Depth + 1, Tag: scope.LinkPrimary})
+					}
 				} else {
 					atomic.AddInt64(&stats.Failed, 1)
+					atomic.AddInt64(&stats.Total, 1)
 				}
 				resultChan <- result
 			}
@@ -226,12 +382,34 @@ func runCrawl() {
 		close(resultChan)
 	}()
 
-	// Write results as NDJSON
+	// Write results as NDJSON, archiving each to the WARC file and the
+	// resume state store as it comes in. Both are only ever touched here,
+	// not from the worker goroutines, so neither needs its own lock.
 	encoder := json.NewEncoder(output)
 	for result := range resultChan {
 		if err := encoder.Encode(result); err != nil {
 			fmt.Fprintf(os.Stderr, "Error encoding result: %v\n", err)
 		}
+
+		if result.Error != "" {
+			if state != nil {
+				state.MarkFailed(result.URL, result.Error)
+			}
+			continue
+		}
+
+		if warcWriter != nil {
+			offset, contentSHA1, err := warcWriter.WriteRecords(result.URL, result.StatusCode, result.respHeaders, result.rawHTML)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing WARC record for %s: %v\n", result.URL, err)
+				continue
+			}
+			if state != nil {
+				state.MarkFetched(result.URL, contentSHA1, offset)
+			}
+		} else if state != nil {
+			state.MarkFetched(result.URL, "", 0)
+		}
 	}
 
 	stats.TotalTimeMs = time.Since(startTime).Milliseconds()
@@ -246,11 +424,13 @@ func runCrawl() {
 }
 
 func runTest(testURL string) {
+	netPolicy := newNetworkPolicy(blockTypes, proxy, proxyUser, proxyPass, insecureTLS)
+
 	// Launch browser
-	l := launcher.New().
+	l := configureLauncherNetwork(launcher.New().
 		Headless(headless).
 		Set("disable-gpu").
-		Set("no-sandbox")
+		Set("no-sandbox"), netPolicy)
 
 	browserURL, err := l.Launch()
 	if err != nil {
@@ -265,14 +445,14 @@ func runTest(testURL string) {
 	}
 	defer browser.Close()
 
-	result := crawlURL(browser, testURL, timeout)
+	result := crawlURL(browser, testURL, timeout, netPolicy)
 
 	// Pretty print result
 	output, _ := json.MarshalIndent(result, "", "  ")
 	fmt.Println(string(output))
 }
 
-func crawlURL(browser *rod.Browser, targetURL string, timeoutSec int) CrawlResult {
+func crawlURL(browser *rod.Browser, targetURL string, timeoutSec int, policy NetworkPolicy) CrawlResult {
 	startTime := time.Now()
 	result := CrawlResult{
 		URL:     targetURL,
@@ -302,6 +482,19 @@ func crawlURL(browser *rod.Browser, targetURL string, timeoutSec int) CrawlResul
 
 	page = page.Context(ctx)
 
+	// Rod doesn't surface the main document's HTTP status/headers through
+	// its normal page API, so intercept every request on this page: besides
+	// letting policy block configured resource types outright, it lets us
+	// read the real status/content-type/final URL (post-redirect) off the
+	// main document's NetworkResponseReceived event, and collect per-
+	// subresource timing/size as a side effect.
+	capture, err := attachNetworkInterception(page, policy)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to attach network interception: %v", err)
+		result.LatencyMs = time.Since(startTime).Milliseconds()
+		return result
+	}
+
 	// Navigate to URL
 	err = page.Navigate(targetURL)
 	if err != nil {
@@ -345,9 +538,32 @@ func crawlURL(browser *rod.Browser, targetURL string, timeoutSec int) CrawlResul
 	// Extract links
 	result.Outlinks, result.InternalLinks = extractLinks(page, sourceDomain)
 
-	// Estimate status code (Rod doesn't easily expose this)
-	result.StatusCode = 200
-	result.ContentType = "text/html"
+	// Extract related resources (images, stylesheets, scripts, and the
+	// url(...) targets inside reachable CSS), regardless of their scope.
+	// They are only listed in result.Related; neither the crawl nor the
+	// WARC output fetches or archives them.
+	result.Related = collectRelated(html, parsed)
+
+	// Give the CDP hook a moment to have seen the document response; it
+	// normally fires well before WaitLoad returns, so this is just a
+	// guard against it still being in flight.
+	capture.WaitMain(2 * time.Second)
+
+	result.StatusCode = capture.Status
+	if result.StatusCode == 0 {
+		result.StatusCode = 200 // hook never fired (e.g. served from cache); assume success
+	}
+	result.FinalURL = capture.FinalURL
+	result.ContentType = capture.Headers.Get("Content-Type")
+	if result.ContentType == "" {
+		result.ContentType = capture.ContentType
+	}
+	if result.ContentType == "" {
+		result.ContentType = "text/html"
+	}
+	result.respHeaders = capture.Headers
+	result.Subresources = capture.Subresources()
+	result.rawHTML = html
 
 	result.LatencyMs = time.Since(startTime).Milliseconds()
 	return result
@@ -441,9 +657,11 @@ func extractLinks(page *rod.Page, sourceDomain string) ([]OutlinkRecord, []strin
 		rel, _ := el.Attribute("rel")
 		isNofollow := rel != nil && strings.Contains(*rel, "nofollow")
 
-		// Determine if external
+		// Determine if external by registrable domain (eTLD+1) rather than
+		// a hardcoded "www." comparison, so e.g. "shop.example.com" and
+		// "www.example.com" are correctly treated as the same site.
 		linkDomain := parsed.Host
-		isExternal := linkDomain != sourceDomain && linkDomain != "www."+sourceDomain && "www."+linkDomain != sourceDomain
+		isExternal := !scope.SameRegistrableDomain(linkDomain, sourceDomain)
 
 		if isExternal {
 			outlinks = append(outlinks, OutlinkRecord{
@@ -465,3 +683,52 @@ func extractLinks(page *rod.Page, sourceDomain string) ([]OutlinkRecord, []strin
 
 	return outlinks, internalLinks
 }
+
+// relatedHTTPClient fetches the handful of linked .css files a page's
+// related resources point at; a short timeout keeps one slow stylesheet
+// from holding up the worker that's rendering the next page.
+var relatedHTTPClient = &http.Client{Timeout: 5 * time.Second}
+
+// collectRelated gathers <img src>, <link rel=stylesheet href>, <script
+// src>, and CSS url(...) references (from both inline <style> blocks and
+// linked .css files) from html, resolved against base. Linked .css files
+// are fetched at most once and are never themselves re-scanned past their
+// own url(...) references, keeping related-resource discovery to one hop
+// off the page regardless of how deep the scope policy would otherwise let
+// primary links go.
+func collectRelated(html string, base *url.URL) []scope.ResourceRecord {
+	related, inlineCSS := scope.ParseRelated(html, base)
+
+	for _, css := range inlineCSS {
+		related = append(related, scope.ParseCSSURLs(css, base)...)
+	}
+
+	fetched := make(map[string]bool)
+	for _, r := range related {
+		if !strings.HasSuffix(strings.ToLower(strings.SplitN(r.URL, "?", 2)[0]), ".css") {
+			continue
+		}
+		if fetched[r.URL] {
+			continue
+		}
+		fetched[r.URL] = true
+
+		resp, err := relatedHTTPClient.Get(r.URL)
+		if err != nil {
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			continue
+		}
+
+		cssBase, err := url.Parse(r.URL)
+		if err != nil {
+			continue
+		}
+		related = append(related, scope.ParseCSSURLs(string(body), cssBase)...)
+	}
+
+	return related
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// NetworkPolicy configures per-page request interception: which resource
+// types get blocked outright before they hit the wire. Proxy/ProxyUser/
+// ProxyPass/InsecureTLS are carried alongside it since they're configured
+// from the same --block et al. flag group, even though Proxy/InsecureTLS
+// are applied at browser-launch time rather than per-request; ProxyUser/
+// ProxyPass are consumed per-request by the FetchAuthRequired handler in
+// attachNetworkInterception, since Chrome only asks for proxy credentials
+// once a page actually issues a request through it.
+type NetworkPolicy struct {
+	BlockTypes  map[proto.NetworkResourceType]bool
+	Proxy       string
+	ProxyUser   string
+	ProxyPass   string
+	InsecureTLS bool
+}
+
+// newNetworkPolicy parses a --block=image,media,font,stylesheet flag value
+// into a NetworkPolicy. Unknown resource types are dropped with a warning
+// rather than failing the whole policy, matching scope.NewPolicy's
+// malformed-pattern handling.
+func newNetworkPolicy(blockFlag, proxy, proxyUser, proxyPass string, insecureTLS bool) NetworkPolicy {
+	policy := NetworkPolicy{
+		BlockTypes:  map[proto.NetworkResourceType]bool{},
+		Proxy:       proxy,
+		ProxyUser:   proxyUser,
+		ProxyPass:   proxyPass,
+		InsecureTLS: insecureTLS,
+	}
+	for _, t := range strings.Split(blockFlag, ",") {
+		switch strings.TrimSpace(strings.ToLower(t)) {
+		case "":
+		case "image":
+			policy.BlockTypes[proto.NetworkResourceTypeImage] = true
+		case "media":
+			policy.BlockTypes[proto.NetworkResourceTypeMedia] = true
+		case "font":
+			policy.BlockTypes[proto.NetworkResourceTypeFont] = true
+		case "stylesheet":
+			policy.BlockTypes[proto.NetworkResourceTypeStylesheet] = true
+		default:
+			fmt.Fprintf(os.Stderr, "Warning: unknown --block resource type %q, skipping\n", t)
+		}
+	}
+	return policy
+}
+
+func (p NetworkPolicy) blocks(t proto.NetworkResourceType) bool {
+	return p.BlockTypes[t]
+}
+
+// SubresourceStat records one intercepted request's resource type, whether
+// it was blocked, and (for requests that were let through) its status code,
+// response size, and how long it took.
+type SubresourceStat struct {
+	URL          string `json:"url"`
+	ResourceType string `json:"resource_type"`
+	Blocked      bool   `json:"blocked"`
+	StatusCode   int    `json:"status_code,omitempty"`
+	Bytes        int64  `json:"bytes,omitempty"`
+	DurationMs   int64  `json:"duration_ms"`
+}
+
+// networkCapture accumulates what attachNetworkInterception observes over
+// one page's lifetime: the main document's real status/content-type/final
+// URL (post-redirect, via NetworkResponseReceived rather than assumed),
+// and per-subresource timing/bytes/block decisions keyed by CDP request ID
+// while in flight.
+type networkCapture struct {
+	mainDone    chan struct{}
+	mainOnce    sync.Once
+	Status      int
+	ContentType string
+	FinalURL    string
+	Headers     http.Header
+
+	mu      sync.Mutex
+	started map[proto.RequestID]time.Time
+	pending map[proto.RequestID]*SubresourceStat
+	done    []SubresourceStat
+}
+
+func newNetworkCapture() *networkCapture {
+	return &networkCapture{
+		mainDone: make(chan struct{}),
+		Headers:  http.Header{},
+		started:  map[proto.RequestID]time.Time{},
+		pending:  map[proto.RequestID]*SubresourceStat{},
+	}
+}
+
+// attachNetworkInterception enables request-pattern interception on page
+// and wires up the CDP events needed to both enforce policy and collect
+// SubresourceStats: FetchRequestPaused (block-or-continue, start timer),
+// FetchAuthRequired (answer the upstream proxy's Basic-auth challenge with
+// policy.ProxyUser/ProxyPass, if set), NetworkResponseReceived (status/
+// content-type/final URL, both for the main document and every
+// subresource), and NetworkLoadingFinished (response size, finalize
+// duration). It must be called before page.Navigate so FetchEnable is
+// active when the first request fires.
+func attachNetworkInterception(page *rod.Page, policy NetworkPolicy) (*networkCapture, error) {
+	capture := newNetworkCapture()
+
+	err := proto.FetchEnable{
+		Patterns:           []*proto.FetchRequestPattern{{URLPattern: "*", RequestStage: proto.FetchRequestStageRequest}},
+		HandleAuthRequests: true,
+	}.Call(page)
+	if err != nil {
+		return nil, fmt.Errorf("could not enable request interception: %w", err)
+	}
+
+	go page.EachEvent(func(e *proto.FetchAuthRequired) {
+		authChallengeResp := proto.FetchAuthChallengeResponse{Response: proto.FetchAuthChallengeResponseResponseDefault}
+		if policy.ProxyUser != "" || policy.ProxyPass != "" {
+			authChallengeResp = proto.FetchAuthChallengeResponse{
+				Response: proto.FetchAuthChallengeResponseResponseProvideCredentials,
+				Username: policy.ProxyUser,
+				Password: policy.ProxyPass,
+			}
+		}
+		proto.FetchContinueWithAuth{RequestID: e.RequestID, AuthChallengeResponse: &authChallengeResp}.Call(page)
+	})()
+
+	go page.EachEvent(func(e *proto.FetchRequestPaused) {
+		capture.mu.Lock()
+		capture.started[e.RequestID] = time.Now()
+		capture.mu.Unlock()
+
+		if policy.blocks(e.ResourceType) {
+			capture.mu.Lock()
+			capture.done = append(capture.done, SubresourceStat{
+				URL:          e.Request.URL,
+				ResourceType: string(e.ResourceType),
+				Blocked:      true,
+			})
+			capture.mu.Unlock()
+			proto.FetchFailRequest{RequestID: e.RequestID, ErrorReason: proto.NetworkErrorReasonBlockedByClient}.Call(page)
+			return
+		}
+
+		capture.mu.Lock()
+		capture.pending[e.RequestID] = &SubresourceStat{URL: e.Request.URL, ResourceType: string(e.ResourceType)}
+		capture.mu.Unlock()
+		proto.FetchContinueRequest{RequestID: e.RequestID}.Call(page)
+	})()
+
+	go page.EachEvent(func(e *proto.NetworkResponseReceived) bool {
+		capture.mu.Lock()
+		if stat, ok := capture.pending[e.RequestID]; ok {
+			stat.StatusCode = int(e.Response.Status)
+		}
+		capture.mu.Unlock()
+
+		if e.Type == proto.NetworkResourceTypeDocument {
+			capture.mainOnce.Do(func() {
+				capture.Status = int(e.Response.Status)
+				capture.ContentType = e.Response.MIMEType
+				capture.FinalURL = e.Response.URL
+				for name, v := range e.Response.Headers {
+					capture.Headers.Set(name, v.String())
+				}
+				close(capture.mainDone)
+			})
+		}
+		return false
+	})()
+
+	go page.EachEvent(func(e *proto.NetworkLoadingFinished) {
+		capture.mu.Lock()
+		defer capture.mu.Unlock()
+		stat, ok := capture.pending[e.RequestID]
+		if !ok {
+			return
+		}
+		delete(capture.pending, e.RequestID)
+		stat.Bytes = int64(e.EncodedDataLength)
+		if start, ok := capture.started[e.RequestID]; ok {
+			stat.DurationMs = time.Since(start).Milliseconds()
+			delete(capture.started, e.RequestID)
+		}
+		capture.done = append(capture.done, *stat)
+	})()
+
+	return capture, nil
+}
+
+// Subresources returns every SubresourceStat observed so far, including
+// requests still awaiting NetworkLoadingFinished (reported with a zero
+// Bytes/DurationMs rather than dropped, so a page that errors mid-load
+// still shows what it attempted).
+func (c *networkCapture) Subresources() []SubresourceStat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := append([]SubresourceStat(nil), c.done...)
+	for _, stat := range c.pending {
+		stats = append(stats, *stat)
+	}
+	return stats
+}
+
+// WaitMain blocks until the main document's response has been observed or
+// timeout elapses, whichever comes first.
+func (c *networkCapture) WaitMain(timeout time.Duration) {
+	select {
+	case <-c.mainDone:
+	case <-time.After(timeout):
+	}
+}
+
+// configureLauncherNetwork applies --proxy/--insecure-tls as Chrome launch
+// flags, mirroring how headless/disable-gpu/no-sandbox are already set in
+// runCrawl/runTest.
+func configureLauncherNetwork(l *launcher.Launcher, policy NetworkPolicy) *launcher.Launcher {
+	if policy.Proxy != "" {
+		l = l.Set("proxy-server", policy.Proxy)
+	}
+	if policy.InsecureTLS {
+		l = l.Set("ignore-certificate-errors")
+	}
+	return l
+}
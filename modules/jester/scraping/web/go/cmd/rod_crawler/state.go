@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// URLState tracks a single URL's progress through the render -> archive
+// pipeline so a `crawl --resume=state.db` run can pick up where a prior one
+// left off, mirroring ccwarc's JobStore (keyed by domain there, by URL here).
+type URLState string
+
+const (
+	URLPending URLState = "pending"
+	URLFetched URLState = "fetched"
+	URLFailed  URLState = "failed"
+)
+
+var urlsBucket = []byte("urls")
+
+// URLRecord is what's persisted per URL in the crawl state store.
+type URLRecord struct {
+	URL         string    `json:"url"`
+	State       URLState  `json:"state"`
+	FetchedAt   time.Time `json:"fetched_at,omitempty"`
+	ContentSHA1 string    `json:"content_sha1,omitempty"`
+	WARCOffset  int64     `json:"warc_offset,omitempty"`
+	FailReason  string    `json:"fail_reason,omitempty"`
+}
+
+// CrawlState is a thin bbolt-backed KV store keyed by URL, giving
+// `crawl --resume` Ctrl-C/resume semantics without pulling in a full job
+// queue system.
+type CrawlState struct {
+	db *bbolt.DB
+}
+
+// OpenCrawlState opens (creating if needed) the bbolt db at path.
+func OpenCrawlState(path string) (*CrawlState, error) {
+	db, err := bbolt.Open(path, 0644, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("could not open crawl state %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(urlsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &CrawlState{db: db}, nil
+}
+
+func (s *CrawlState) Close() error {
+	return s.db.Close()
+}
+
+func (s *CrawlState) Get(url string) (URLRecord, bool) {
+	var rec URLRecord
+	found := false
+	s.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(urlsBucket).Get([]byte(url))
+		if v == nil {
+			return nil
+		}
+		if err := json.Unmarshal(v, &rec); err == nil {
+			found = true
+		}
+		return nil
+	})
+	return rec, found
+}
+
+// MarkFetched records url as rendered and archived: contentSHA1 is the
+// base32 WARC-Payload-Digest of its rendered HTML and warcOffset is where
+// its request/response record pair starts in the WARC file, so a later --resume run can
+// both skip it and verify the WARC wasn't truncated mid-write.
+func (s *CrawlState) MarkFetched(url, contentSHA1 string, warcOffset int64) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		rec := URLRecord{
+			URL:         url,
+			State:       URLFetched,
+			FetchedAt:   time.Now(),
+			ContentSHA1: contentSHA1,
+			WARCOffset:  warcOffset,
+		}
+		encoded, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(urlsBucket).Put([]byte(url), encoded)
+	})
+}
+
+// MarkFailed records url as having failed this attempt; it stays eligible
+// for retry on the next --resume run.
+func (s *CrawlState) MarkFailed(url, reason string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		rec := URLRecord{URL: url, State: URLFailed, FailReason: reason}
+		encoded, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(urlsBucket).Put([]byte(url), encoded)
+	})
+}
+
+// PendingURLs returns the subset of urls that still need work: never seen,
+// or not yet URLFetched. Used when --resume is set so a re-run doesn't
+// re-render and re-append WARC records for URLs already archived.
+func (s *CrawlState) PendingURLs(urls []string) []string {
+	var pending []string
+	for _, u := range urls {
+		rec, found := s.Get(u)
+		if found && rec.State == URLFetched {
+			continue
+		}
+		pending = append(pending, u)
+	}
+	return pending
+}
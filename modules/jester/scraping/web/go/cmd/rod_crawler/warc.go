@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// WarcWriter appends `request`/`response` record pairs to a WARC/1.1 file,
+// one pair per crawled page. Unlike cclinks' WarcWriter (which copies
+// pre-existing Common Crawl records byte-for-byte), rod_crawler has no
+// upstream WARC to copy from — the browser does its own fetching — so both
+// records here are synthesized from what Rod rendered and what the
+// NetworkResponseReceived CDP hook observed.
+type WarcWriter struct {
+	file    *os.File
+	written int64
+}
+
+// NewWarcWriter opens path for appending, creating it if needed, so a
+// --resume run adds to the records an earlier run already wrote.
+func NewWarcWriter(path string) (*WarcWriter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not create WARC output %s: %w", path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not stat WARC output %s: %w", path, err)
+	}
+	return &WarcWriter{file: f, written: info.Size()}, nil
+}
+
+func (w *WarcWriter) Close() error {
+	return w.file.Close()
+}
+
+// Offset reports where the next WriteRecords call's request record will
+// start; CrawlState persists this as a URL's warc_offset.
+func (w *WarcWriter) Offset() int64 {
+	return w.written
+}
+
+// newWarcRecordID mints a urn:uuid WARC-Record-ID for a record we author
+// ourselves, since rod_crawler has no original record to inherit one from.
+func newWarcRecordID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// WriteRecords appends a synthesized `request` record (the GET the browser
+// made) followed by a `response` record (statusCode/headers captured via
+// the NetworkResponseReceived CDP hook, body is the final rendered HTML) to
+// the WARC file, each as its own gzip member. It returns the offset the pair
+// started at and the base32-encoded sha1 WARC-Payload-Digest of body, the
+// same digest encoding ccwarc reads back off real Common Crawl records.
+func (w *WarcWriter) WriteRecords(targetURL string, statusCode int, headers http.Header, body string) (offset int64, contentSHA1 string, err error) {
+	offset = w.written
+	date := time.Now().UTC().Format("2006-01-02T15:04:05Z")
+
+	sum := sha1.Sum([]byte(body))
+	digest := base32.StdEncoding.EncodeToString(sum[:])
+
+	requestID := newWarcRecordID()
+	responseID := newWarcRecordID()
+
+	reqPayload := fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\nUser-Agent: rod_crawler\r\nAccept: text/html\r\n\r\n",
+		targetURL, hostOf(targetURL))
+
+	var reqHeader strings.Builder
+	reqHeader.WriteString("WARC/1.1\r\n")
+	reqHeader.WriteString("WARC-Type: request\r\n")
+	reqHeader.WriteString(fmt.Sprintf("WARC-Record-ID: %s\r\n", requestID))
+	reqHeader.WriteString(fmt.Sprintf("WARC-Date: %s\r\n", date))
+	reqHeader.WriteString(fmt.Sprintf("WARC-Target-URI: %s\r\n", targetURL))
+	reqHeader.WriteString(fmt.Sprintf("WARC-Concurrent-To: %s\r\n", responseID))
+	reqHeader.WriteString("Content-Type: application/http; msgtype=request\r\n")
+	reqHeader.WriteString(fmt.Sprintf("Content-Length: %d\r\n", len(reqPayload)))
+	reqHeader.WriteString("\r\n")
+
+	if err = w.writeMember(reqHeader.String(), reqPayload); err != nil {
+		return 0, "", err
+	}
+
+	var statusLine strings.Builder
+	statusLine.WriteString(fmt.Sprintf("HTTP/1.1 %d %s\r\n", statusCode, http.StatusText(statusCode)))
+	for name, values := range headers {
+		for _, v := range values {
+			statusLine.WriteString(fmt.Sprintf("%s: %s\r\n", name, v))
+		}
+	}
+	statusLine.WriteString("\r\n")
+	respPayload := statusLine.String() + body
+
+	var respHeader strings.Builder
+	respHeader.WriteString("WARC/1.1\r\n")
+	respHeader.WriteString("WARC-Type: response\r\n")
+	respHeader.WriteString(fmt.Sprintf("WARC-Record-ID: %s\r\n", responseID))
+	respHeader.WriteString(fmt.Sprintf("WARC-Date: %s\r\n", date))
+	respHeader.WriteString(fmt.Sprintf("WARC-Target-URI: %s\r\n", targetURL))
+	respHeader.WriteString(fmt.Sprintf("WARC-Concurrent-To: %s\r\n", requestID))
+	respHeader.WriteString(fmt.Sprintf("WARC-Payload-Digest: sha1:%s\r\n", digest))
+	respHeader.WriteString("Content-Type: application/http; msgtype=response\r\n")
+	respHeader.WriteString(fmt.Sprintf("Content-Length: %d\r\n", len(respPayload)))
+	respHeader.WriteString("\r\n")
+
+	if err = w.writeMember(respHeader.String(), respPayload); err != nil {
+		return 0, "", err
+	}
+
+	return offset, digest, nil
+}
+
+// writeMember gzip-compresses header+body as a single WARC record and
+// appends it to the file, keeping w.written accurate for Offset.
+func (w *WarcWriter) writeMember(header, body string) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(header)); err != nil {
+		return err
+	}
+	if _, err := gz.Write([]byte(body)); err != nil {
+		return err
+	}
+	gz.Write([]byte("\r\n\r\n"))
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	n, err := w.file.Write(buf.Bytes())
+	w.written += int64(n)
+	return err
+}
+
+func hostOf(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}